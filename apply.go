@@ -0,0 +1,221 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"sort"
+	"strconv"
+
+	"github.com/fatih/color"
+	"github.com/theist/librato-alerts-cli/librato"
+)
+
+// actions taken by apply to converge alerts to their definitions
+const (
+	actionCreate = "create"
+	actionUpdate = "update"
+	actionDelete = "delete"
+)
+
+// alertChange is a change needed for an alert to match its definition.
+type alertChange struct {
+	action string
+	// alert is the alert as it should be, or the one to delete
+	alert librato.Alert
+	// current is the alert as read, before the change
+	current librato.Alert
+	fields  []fieldChange
+	// source is the file of the definition
+	source string
+}
+
+// key identifies the change of alert, by id or by name for new alerts.
+func changeKey(alert librato.Alert) string {
+	if alert.ID == 0 {
+		return "new " + alert.Name
+	}
+	return strconv.Itoa(alert.ID)
+}
+
+// definitionsDir returns the definitions directory given in args, ./alerts
+// by default.
+func definitionsDir(args []string) string {
+	if len(args) > 0 {
+		return args[0]
+	}
+	return "alerts"
+}
+
+// convergeChanges returns the changes needed for the alerts to match the
+// definitions in dir, deleting the alerts not defined with --prune, and the
+// number of alerts already matching.
+func convergeChanges(dir string) ([]alertChange, int) {
+	defs, err := readDefinitions(dir)
+	if err != nil {
+		fatal("Error reading alert definitions > ", err)
+	}
+	err, alerts := getAllAlertList()
+	if err != nil {
+		fatal("Error getting alerts > ", err)
+	}
+	services, err := fetchServiceIndex(defs)
+	if err != nil {
+		fatal("Error getting services > ", err)
+	}
+
+	byName := map[string][]librato.Alert{}
+	for _, alert := range *alerts {
+		byName[alert.Name] = append(byName[alert.Name], alert)
+	}
+	var changes []alertChange
+	unchanged := 0
+	defined := map[string]bool{}
+	for _, def := range defs {
+		defined[def.Name] = true
+		existing := byName[def.Name]
+		if len(existing) > 1 {
+			fatalf("%v: %v alerts are named %v, rename them to manage it", def.path, len(existing), def.Name)
+		}
+		if len(existing) == 0 {
+			alert := librato.Alert{Active: def.createActive()}
+			if err := def.apply(&alert, services); err != nil {
+				fatalf("%v: %v", def.path, err)
+			}
+			fields, err := def.createChanges()
+			if err != nil {
+				fatal("Error comparing alerts > ", err)
+			}
+			changes = append(changes, alertChange{action: actionCreate, alert: alert, fields: fields, source: def.path})
+			continue
+		}
+
+		current := existing[0]
+		fields, err := diffFields(newDefinition(current), def)
+		if err != nil {
+			fatal("Error comparing alerts > ", err)
+		}
+		if len(fields) == 0 {
+			unchanged++
+			continue
+		}
+		alert := current
+		if err := def.apply(&alert, services); err != nil {
+			fatalf("%v: %v", def.path, err)
+		}
+		changes = append(changes, alertChange{action: actionUpdate, alert: alert, current: current, fields: fields, source: def.path})
+	}
+
+	if opts.prune {
+		sorted := append(alertList(nil), *alerts...)
+		sort.SliceStable(sorted, func(i, j int) bool { return sorted[i].ID < sorted[j].ID })
+		for _, alert := range sorted {
+			if !defined[alert.Name] {
+				changes = append(changes, alertChange{action: actionDelete, alert: alert})
+			}
+		}
+	}
+	return changes, unchanged
+}
+
+// printChanges prints every change with its field level diff followed by a
+// count of changes.
+func printChanges(changes []alertChange, unchanged int) {
+	counts := map[string]int{}
+	for _, change := range changes {
+		counts[change.action]++
+		switch change.action {
+		case actionCreate:
+			fmt.Printf("%v %v (%v)\n", color.HiGreenString("+ create"), change.alert.Name, change.source)
+		case actionUpdate:
+			fmt.Printf("%v %v (%v)\n", color.HiYellowString("~ update"), change.alert.Name, change.alert.ID)
+		case actionDelete:
+			fmt.Printf("%v %v (%v)\n", color.HiRedString("- delete"), change.alert.Name, change.alert.ID)
+		}
+		writeChanges(os.Stdout, change.fields, "    ")
+	}
+	if len(changes) > 0 {
+		fmt.Println()
+	}
+	fmt.Printf("%v to create, %v to update, %v to delete, %v unchanged\n",
+		counts[actionCreate], counts[actionUpdate], counts[actionDelete], unchanged)
+}
+
+// planAlerts prints what apply would change.
+func planAlerts(args []string) {
+	changes, unchanged := convergeChanges(definitionsDir(args))
+	printChanges(changes, unchanged)
+}
+
+// applyAlerts creates, updates and with --prune deletes alerts until they
+// match the definitions, after showing the changes and asking to confirm.
+func applyAlerts(args []string) {
+	changes, unchanged := convergeChanges(definitionsDir(args))
+	printChanges(changes, unchanged)
+	if len(changes) == 0 {
+		return
+	}
+	if opts.dryRun {
+		fmt.Println("dry run, nothing was updated")
+		return
+	}
+	if !opts.yes && !haveTerminal() {
+		usage("No terminal to ask for confirmation, pass --yes to go on")
+	}
+	if !opts.yes && !confirm("Apply these changes?") {
+		fmt.Println("nothing was updated")
+		return
+	}
+
+	byKey := map[string]alertChange{}
+	alerts := make([]librato.Alert, len(changes))
+	for i, change := range changes {
+		byKey[changeKey(change.alert)] = change
+		alerts[i] = change.alert
+	}
+	result := runBulk(alerts, func(alert librato.Alert) (librato.Alert, bool, error) {
+		change := byKey[changeKey(alert)]
+		ctx := context.Background()
+		var err error
+		switch change.action {
+		case actionCreate:
+			var created *librato.Alert
+			if created, err = client.Alerts.Create(ctx, &alert); err == nil {
+				alert = *created
+			}
+		case actionUpdate:
+			if err = checkVersion(change.current); err == nil {
+				err = client.Alerts.Update(ctx, &alert)
+			}
+		case actionDelete:
+			// backed up like the ones deleted by the delete mode, logging
+			// its own failures
+			if alert, err = trashAndDelete(alert); err != nil {
+				return alert, false, err
+			}
+		}
+		if isConflict(err) {
+			logLine(err.Error())
+			return alert, false, err
+		}
+		if err != nil {
+			logLine(fmt.Sprintf("Error trying to %v alert %v: %v", change.action, alert.Name, err))
+			return alert, false, err
+		}
+		printLine(fmt.Sprintf("%vd %v (%v)", change.action, alert.Name, alert.ID))
+		return alert, true, nil
+	}, nil)
+
+	counts := map[string]int{}
+	for _, alert := range result.changed {
+		// created alerts come back with their new id
+		action := actionCreate
+		if change, ok := byKey[changeKey(alert)]; ok {
+			action = change.action
+		}
+		counts[action]++
+	}
+	fmt.Printf("%v created, %v updated, %v deleted, %v failed\n",
+		counts[actionCreate], counts[actionUpdate], counts[actionDelete], len(result.failed))
+	result.exitOnFailure()
+}
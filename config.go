@@ -0,0 +1,216 @@
+package main
+
+import (
+	"bufio"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"regexp"
+	"sort"
+	"strings"
+	"text/tabwriter"
+
+	"github.com/joho/godotenv"
+	"github.com/mitchellh/go-homedir"
+	"github.com/theist/librato-alerts-cli/librato"
+)
+
+// profileVars are the settings a profile can hold.
+var profileVars = append([]string{"LIBRATO_MAIL", "LIBRATO_API_URL"}, tokenVars...)
+
+// defaultSettings are the profile settings found in the environment and the
+// config files before activating a profile.
+var defaultSettings = map[string]string{}
+
+// settingOrigins tells where each profile setting in effect was read from.
+var settingOrigins = map[string]string{}
+
+// configSources are the config files loadConfig looked at, in precedence
+// order.
+var configSources []configSource
+
+// configSource describes a config file read by loadConfig.
+type configSource struct {
+	path     string
+	found    bool
+	vars     int
+	profiles []string
+}
+
+var sectionHeader = regexp.MustCompile(`^\s*\[([A-Za-z0-9_.-]+)\]\s*$`)
+
+// configFile is the content of a .env style config file. Settings before any
+// [name] section header are loaded into the environment as usual, each
+// section holds the settings of a named profile.
+type configFile struct {
+	found    bool
+	vars     map[string]string
+	profiles map[string]map[string]string
+}
+
+// userConfigPath returns the path of the config file in the home dir.
+func userConfigPath() string {
+	path, _ := homedir.Expand("~/.librato-alerts-cli")
+	return path
+}
+
+// readConfigFile parses path, returning an empty config if it doesn't exist.
+func readConfigFile(path string) (*configFile, error) {
+	cfg := &configFile{vars: map[string]string{}, profiles: map[string]map[string]string{}}
+	data, err := ioutil.ReadFile(path)
+	if os.IsNotExist(err) {
+		return cfg, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	cfg.found = true
+
+	sections := map[string]*strings.Builder{"": {}}
+	current := ""
+	scanner := bufio.NewScanner(strings.NewReader(string(data)))
+	for scanner.Scan() {
+		line := scanner.Text()
+		if match := sectionHeader.FindStringSubmatch(line); match != nil {
+			current = match[1]
+			if sections[current] == nil {
+				sections[current] = &strings.Builder{}
+			}
+			continue
+		}
+		sections[current].WriteString(line + "\n")
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+
+	for name, section := range sections {
+		vars, err := godotenv.Unmarshal(section.String())
+		if err != nil {
+			return nil, fmt.Errorf("%v: %w", path, err)
+		}
+		if name == "" {
+			cfg.vars = vars
+		} else {
+			cfg.profiles[name] = vars
+		}
+	}
+	return cfg, nil
+}
+
+// loadConfig loads the local .env file and the home config file into the
+// environment without overriding variables already set, the local file
+// taking precedence. It returns the profiles defined in both, the local file
+// ones replacing home ones with the same name.
+func loadConfig() (map[string]map[string]string, error) {
+	profiles := map[string]map[string]string{}
+	for _, key := range profileVars {
+		if _, present := os.LookupEnv(key); present {
+			settingOrigins[key] = "environment"
+		}
+	}
+	for _, path := range []string{".env", userConfigPath()} {
+		cfg, err := readConfigFile(path)
+		if err != nil {
+			return nil, err
+		}
+		configSources = append(configSources, configSource{path: path, found: cfg.found, vars: len(cfg.vars), profiles: profileNames(cfg.profiles)})
+		for key, value := range cfg.vars {
+			if _, present := os.LookupEnv(key); !present {
+				os.Setenv(key, value)
+				settingOrigins[key] = path
+			}
+		}
+		for name, vars := range cfg.profiles {
+			if _, defined := profiles[name]; !defined {
+				profiles[name] = vars
+			}
+		}
+	}
+	return profiles, nil
+}
+
+// activateProfile sets the settings of the profile selected with --profile
+// or LIBRATO_PROFILE, overriding the environment. Flags given explicitly
+// still take precedence.
+func activateProfile(profiles map[string]map[string]string) error {
+	for _, key := range profileVars {
+		defaultSettings[key] = os.Getenv(key)
+	}
+	if opts.profile == "" {
+		return nil
+	}
+	vars, ok := profiles[opts.profile]
+	if !ok {
+		return fmt.Errorf("unknown profile %v, defined ones are: %v", opts.profile, strings.Join(profileNames(profiles), ", "))
+	}
+	// a profile giving its token in any way replaces every token setting
+	for _, key := range tokenVars {
+		if _, set := vars[key]; set {
+			for _, tokenKey := range tokenVars {
+				os.Unsetenv(tokenKey)
+				delete(settingOrigins, tokenKey)
+			}
+			break
+		}
+	}
+	for _, key := range profileVars {
+		if value, set := vars[key]; set {
+			os.Setenv(key, value)
+			settingOrigins[key] = "profile " + opts.profile
+		}
+	}
+	if !opts.explicit["api-url"] {
+		loadAPIURL()
+	}
+	return nil
+}
+
+func profileNames(profiles map[string]map[string]string) []string {
+	names := make([]string, 0, len(profiles))
+	for name := range profiles {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// profileLabel describes the active profile and account.
+func profileLabel() string {
+	name := opts.profile
+	if name == "" {
+		name = "default"
+	}
+	return fmt.Sprintf("profile %v (%v at %v)", name, os.Getenv("LIBRATO_MAIL"), opts.apiURL)
+}
+
+// printProfiles lists the defined profiles, marking the active one.
+func printProfiles(profiles map[string]map[string]string) {
+	tw := tabwriter.NewWriter(os.Stdout, 0, 4, 2, ' ', 0)
+	fmt.Fprintln(tw, "  PROFILE\tMAIL\tAPI URL")
+	names := append([]string{""}, profileNames(profiles)...)
+	for _, name := range names {
+		mark, label, vars := "  ", name, profiles[name]
+		if name == "" {
+			label, vars = "default", defaultSettings
+		}
+		if name == opts.profile {
+			mark = "* "
+		}
+		apiURL := profileSetting(vars, "LIBRATO_API_URL")
+		if apiURL == "" {
+			apiURL = librato.DefaultBaseURL
+		}
+		fmt.Fprintf(tw, "%v%v\t%v\t%v\n", mark, label, profileSetting(vars, "LIBRATO_MAIL"), apiURL)
+	}
+	tw.Flush()
+}
+
+// profileSetting returns the value of key a profile with vars would use,
+// inheriting the settings it doesn't define as activateProfile does.
+func profileSetting(vars map[string]string, key string) string {
+	if value, set := vars[key]; set {
+		return value
+	}
+	return defaultSettings[key]
+}
@@ -0,0 +1,51 @@
+package main
+
+import (
+	"bufio"
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/mattn/go-isatty"
+)
+
+// terminal returns the terminal to ask on, stdin or the controlling
+// terminal when stdin is piped, nil when there is none.
+func terminal() *os.File {
+	if isatty.IsTerminal(os.Stdin.Fd()) {
+		return os.Stdin
+	}
+	tty, err := os.Open("/dev/tty")
+	if err != nil {
+		return nil
+	}
+	return tty
+}
+
+// haveTerminal tells if confirm can ask.
+func haveTerminal() bool {
+	tty := terminal()
+	if tty != nil && tty != os.Stdin {
+		tty.Close()
+	}
+	return tty != nil
+}
+
+// confirm asks question on the terminal and tells if it was answered yes,
+// exiting when there is no terminal to ask on.
+func confirm(question string) bool {
+	tty := terminal()
+	if tty == nil {
+		usage("No terminal to ask for confirmation")
+	}
+	if tty != os.Stdin {
+		defer tty.Close()
+	}
+	fmt.Fprintf(os.Stderr, "%v [y/N] ", question)
+	answer, _ := bufio.NewReader(tty).ReadString('\n')
+	switch strings.ToLower(strings.TrimSpace(answer)) {
+	case "y", "yes":
+		return true
+	}
+	return false
+}
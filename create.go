@@ -0,0 +1,128 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"time"
+
+	"github.com/theist/librato-alerts-cli/librato"
+)
+
+// createFlags describe the alert built by create when no file is given.
+type createFlags struct {
+	name            string
+	description     string
+	metric          string
+	condType        string
+	threshold       float64
+	duration        time.Duration
+	summaryFunction string
+	services        stringList
+	rearm           time.Duration
+}
+
+// createFlagNames are the flags building the alert, refused along a file.
+var createFlagNames = []string{"name", "description", "metric", "type", "threshold", "duration", "summary-function", "service", "rearm"}
+
+// flagsDefinition returns the definition of a single condition alert built
+// from the create flags.
+func flagsDefinition() alertDefinition {
+	f := opts.create
+	if f.name == "" || f.metric == "" {
+		usage("create mode requires --name and --metric, or a definition file")
+	}
+	if f.condType != "absent" && !opts.explicit["threshold"] {
+		usage("--threshold is required for ", f.condType, " conditions")
+	}
+	condition := definitionCondition{
+		Type:            f.condType,
+		MetricName:      f.metric,
+		Threshold:       f.threshold,
+		Duration:        int(f.duration / time.Second),
+		SummaryFunction: f.summaryFunction,
+	}
+	if f.condType == "absent" && !opts.explicit["summary-function"] {
+		condition.SummaryFunction = ""
+	}
+	return alertDefinition{
+		Name:         f.name,
+		Description:  f.description,
+		Conditions:   []definitionCondition{condition},
+		Services:     f.services,
+		RearmSeconds: int(f.rearm / time.Second),
+	}
+}
+
+// fileDefinition reads the definition of the alert to create from path, or
+// from stdin when path is -.
+func fileDefinition(path string) alertDefinition {
+	for _, name := range createFlagNames {
+		if opts.explicit[name] {
+			usagef("--%v can't be given along a definition file", name)
+		}
+	}
+	var data []byte
+	var err error
+	if path == "-" {
+		data, err = ioutil.ReadAll(os.Stdin)
+	} else {
+		data, err = ioutil.ReadFile(path)
+	}
+	if err != nil {
+		fatal("Error reading alert definition > ", err)
+	}
+	def, err := parseDefinition(data)
+	if err != nil {
+		usage("Invalid alert definition ", path, " > ", err)
+	}
+	def.path = path
+	return def
+}
+
+// createAlert creates an alert from the definition file given in args or
+// from the create flags, printing its id.
+func createAlert(args []string) {
+	var def alertDefinition
+	switch len(args) {
+	case 0:
+		def = flagsDefinition()
+		if err := def.validate(); err != nil {
+			usage("Invalid alert > ", err)
+		}
+		def.path = "flags"
+	case 1:
+		def = fileDefinition(args[0])
+	default:
+		usage("create mode takes a single definition file")
+	}
+
+	services, err := fetchServiceIndex([]alertDefinition{def})
+	if err != nil {
+		fatal("Error getting services > ", err)
+	}
+	alert := librato.Alert{Active: def.createActive()}
+	if err := def.apply(&alert, services); err != nil {
+		fatal("Invalid alert > ", err)
+	}
+	if opts.dryRun {
+		fields, err := def.createChanges()
+		if err != nil {
+			fatal("Error comparing alerts > ", err)
+		}
+		printChanges([]alertChange{{action: actionCreate, alert: alert, fields: fields, source: def.path}}, 0)
+		fmt.Println("dry run, nothing was updated")
+		return
+	}
+
+	created, err := client.Alerts.Create(context.Background(), &alert)
+	if err != nil {
+		fatal("Error creating alert > ", err)
+	}
+	if machineOutput() {
+		printRecords(newRecords([]librato.Alert{*created}, nil))
+		return
+	}
+	fmt.Println(created.ID)
+}
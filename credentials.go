@@ -0,0 +1,99 @@
+package main
+
+import (
+	"bytes"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"os/exec"
+	"runtime"
+	"strings"
+
+	"github.com/mitchellh/go-homedir"
+)
+
+// tokenVars are the settings an API token can come from, in precedence order.
+var tokenVars = []string{"LIBRATO_TOKEN", "LIBRATO_TOKEN_COMMAND", "LIBRATO_TOKEN_FILE"}
+
+// hasTokenSource tells if any setting providing the API token is set.
+func hasTokenSource() bool {
+	for _, key := range tokenVars {
+		if os.Getenv(key) != "" {
+			return true
+		}
+	}
+	return false
+}
+
+// tokenSource describes where the API token is taken from.
+func tokenSource() string {
+	for _, key := range tokenVars {
+		if os.Getenv(key) != "" {
+			return key
+		}
+	}
+	return "none"
+}
+
+// resolveToken returns the API token from LIBRATO_TOKEN, the output of
+// LIBRATO_TOKEN_COMMAND or the content of LIBRATO_TOKEN_FILE.
+func resolveToken() (string, error) {
+	if token := os.Getenv("LIBRATO_TOKEN"); token != "" {
+		return token, nil
+	}
+	if command := os.Getenv("LIBRATO_TOKEN_COMMAND"); command != "" {
+		return tokenFromCommand(command)
+	}
+	if path := os.Getenv("LIBRATO_TOKEN_FILE"); path != "" {
+		return tokenFromFile(path)
+	}
+	return "", fmt.Errorf("no API token configured, set one of %v", strings.Join(tokenVars, ", "))
+}
+
+// tokenFromCommand runs command with the shell and takes the first line of
+// its output as the token, like `pass show librato` prints it. The command
+// gets no stdin, which may hold piped alerts, but its stderr is kept so it
+// can prompt for a passphrase.
+func tokenFromCommand(command string) (string, error) {
+	shell, flag := "sh", "-c"
+	if runtime.GOOS == "windows" {
+		shell, flag = "cmd", "/C"
+	}
+	cmd := exec.Command(shell, flag, command)
+	cmd.Stderr = os.Stderr
+	var out bytes.Buffer
+	cmd.Stdout = &out
+	if err := cmd.Run(); err != nil {
+		return "", fmt.Errorf("LIBRATO_TOKEN_COMMAND failed: %w", err)
+	}
+	return firstLine(out.String(), "LIBRATO_TOKEN_COMMAND output")
+}
+
+// tokenFromFile reads the token from the first line of path, refusing files
+// other users can read.
+func tokenFromFile(path string) (string, error) {
+	path, err := homedir.Expand(path)
+	if err != nil {
+		return "", err
+	}
+	info, err := os.Stat(path)
+	if err != nil {
+		return "", fmt.Errorf("LIBRATO_TOKEN_FILE: %w", err)
+	}
+	if runtime.GOOS != "windows" && info.Mode().Perm()&0044 != 0 {
+		return "", fmt.Errorf("LIBRATO_TOKEN_FILE %v can be read by other users (mode %v), run chmod 600 %v", path, info.Mode().Perm(), path)
+	}
+	data, err := ioutil.ReadFile(path)
+	if err != nil {
+		return "", fmt.Errorf("LIBRATO_TOKEN_FILE: %w", err)
+	}
+	return firstLine(string(data), path)
+}
+
+func firstLine(text, origin string) (string, error) {
+	line := strings.TrimSpace(strings.SplitN(text, "\n", 2)[0])
+	if line == "" {
+		return "", fmt.Errorf("%v is empty", origin)
+	}
+	return line, nil
+}
@@ -0,0 +1,59 @@
+package main
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"testing"
+)
+
+func TestTokenFromFile(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("file permissions are not checked on windows")
+	}
+	tests := []struct {
+		name    string
+		content string
+		mode    os.FileMode
+		want    string
+		wantErr string
+	}{
+		{name: "private", content: "secret\nsecond line\n", mode: 0600, want: "secret"},
+		{name: "owner read only", content: "  secret  \n", mode: 0400, want: "secret"},
+		{name: "readable by everyone", content: "secret\n", mode: 0644, wantErr: "can be read by other users"},
+		{name: "readable by group", content: "secret\n", mode: 0640, wantErr: "can be read by other users"},
+		{name: "readable by others", content: "secret\n", mode: 0604, wantErr: "can be read by other users"},
+		{name: "empty", content: "\n", mode: 0600, wantErr: "is empty"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			path := filepath.Join(t.TempDir(), "token")
+			if err := ioutil.WriteFile(path, []byte(tt.content), 0600); err != nil {
+				t.Fatal(err)
+			}
+			// set after writing, as the umask applies to WriteFile
+			if err := os.Chmod(path, tt.mode); err != nil {
+				t.Fatal(err)
+			}
+			token, err := tokenFromFile(path)
+			if tt.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Fatalf("got token %q and error %v, want %q", token, err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if token != tt.want {
+				t.Errorf("got %q, want %q", token, tt.want)
+			}
+		})
+	}
+
+	if _, err := tokenFromFile(filepath.Join(t.TempDir(), "missing")); err == nil {
+		t.Errorf("missing file accepted")
+	}
+}
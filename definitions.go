@@ -0,0 +1,384 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+
+	"github.com/theist/librato-alerts-cli/librato"
+	"sigs.k8s.io/yaml"
+)
+
+// conditionTypes are the valid condition types.
+var conditionTypes = []string{"above", "below", "absent"}
+
+// definitionExts are the extensions of the files read as alert definitions.
+var definitionExts = []string{".yaml", ".yml", ".json"}
+
+// alertDefinition is an alert as kept in the files written by export and read
+// by plan and apply. Alerts are identified by name and refer to their
+// services by title. Whether an alert is active is left out, it is managed
+// with enable, disable and snooze, files can only set it for the alerts
+// created from them.
+type alertDefinition struct {
+	Name           string                `json:"name"`
+	Description    string                `json:"description,omitempty"`
+	Conditions     []definitionCondition `json:"conditions"`
+	Services       []string              `json:"services,omitempty"`
+	Attributes     librato.Attributes    `json:"attributes,omitempty"`
+	RearmSeconds   int                   `json:"rearm_seconds"`
+	RearmPerSignal bool                  `json:"rearm_per_signal,omitempty"`
+	Md             bool                  `json:"md,omitempty"`
+
+	// path is the file the definition was read from
+	path string
+	// active is the active field of the file, if it had one
+	active *bool
+}
+
+// definitionCondition is a librato.Condition without the id set by the API.
+type definitionCondition struct {
+	Type            string                 `json:"type"`
+	MetricName      string                 `json:"metric_name"`
+	Source          string                 `json:"source,omitempty"`
+	Tags            []librato.ConditionTag `json:"tags,omitempty"`
+	Threshold       float64                `json:"threshold"`
+	Duration        int                    `json:"duration"`
+	SummaryFunction string                 `json:"summary_function,omitempty"`
+	DetectReset     bool                   `json:"detect_reset,omitempty"`
+}
+
+func hasString(values []string, s string) bool {
+	for _, value := range values {
+		if value == s {
+			return true
+		}
+	}
+	return false
+}
+
+// newDefinition returns the definition of alert.
+func newDefinition(alert librato.Alert) alertDefinition {
+	def := alertDefinition{
+		Name:           alert.Name,
+		Description:    alert.Description,
+		Attributes:     alert.Attributes,
+		RearmSeconds:   alert.RearmSeconds,
+		RearmPerSignal: alert.RearmPerSignal,
+		Md:             alert.Md,
+	}
+	for _, c := range alert.Conditions {
+		def.Conditions = append(def.Conditions, definitionCondition{
+			Type:            c.Type,
+			MetricName:      c.MetricName,
+			Source:          c.Source,
+			Tags:            c.Tags,
+			Threshold:       c.Threshold,
+			Duration:        c.Duration,
+			SummaryFunction: c.SummaryFunction,
+			DetectReset:     c.DetectReset,
+		})
+	}
+	for _, s := range alert.Services {
+		def.Services = append(def.Services, s.Title)
+	}
+	return def
+}
+
+// validate checks the definition has what the API requires.
+func (d alertDefinition) validate() error {
+	if strings.TrimSpace(d.Name) == "" {
+		return fmt.Errorf("name is required")
+	}
+	if len(d.Conditions) == 0 {
+		return fmt.Errorf("at least one condition is required")
+	}
+	for i, c := range d.Conditions {
+		if !hasString(conditionTypes, c.Type) {
+			return fmt.Errorf("conditions[%v].type must be one of %v, not %q", i, strings.Join(conditionTypes, ", "), c.Type)
+		}
+		if c.MetricName == "" {
+			return fmt.Errorf("conditions[%v].metric_name is required", i)
+		}
+		if c.Type != "absent" && c.SummaryFunction == "" {
+			return fmt.Errorf("conditions[%v].summary_function is required for %v conditions", i, c.Type)
+		}
+		if c.Duration < 0 {
+			return fmt.Errorf("conditions[%v].duration can't be negative", i)
+		}
+	}
+	if d.RearmSeconds < 0 {
+		return fmt.Errorf("rearm_seconds can't be negative")
+	}
+	return nil
+}
+
+// apply sets the fields of alert to the definition, looking up its services
+// by title. Condition ids are kept by position.
+func (d alertDefinition) apply(alert *librato.Alert, services serviceIndex) error {
+	attached, err := services.lookup(d.Services)
+	if err != nil {
+		return err
+	}
+	conditions := make([]librato.Condition, len(d.Conditions))
+	for i, c := range d.Conditions {
+		conditions[i] = librato.Condition{
+			Type:            c.Type,
+			MetricName:      c.MetricName,
+			Source:          c.Source,
+			Tags:            c.Tags,
+			Threshold:       c.Threshold,
+			Duration:        c.Duration,
+			SummaryFunction: c.SummaryFunction,
+			DetectReset:     c.DetectReset,
+		}
+		if i < len(alert.Conditions) {
+			conditions[i].ID = alert.Conditions[i].ID
+		}
+	}
+	alert.Name = d.Name
+	alert.Description = d.Description
+	alert.Conditions = conditions
+	alert.Services = attached
+	alert.Attributes = d.Attributes
+	alert.RearmSeconds = d.RearmSeconds
+	alert.RearmPerSignal = d.RearmPerSignal
+	alert.Md = d.Md
+	return nil
+}
+
+// createActive tells if the alerts created from the definition are active,
+// which they are unless its file says otherwise.
+func (d alertDefinition) createActive() bool {
+	return d.active == nil || *d.active
+}
+
+// createChanges returns the fields of the alert created from the definition
+// as changes from nothing.
+func (d alertDefinition) createChanges() ([]fieldChange, error) {
+	fields, err := diffFields(struct{}{}, d)
+	if err != nil || d.active == nil {
+		return fields, err
+	}
+	// active sorts before every other field
+	return append([]fieldChange{{path: "active", after: *d.active}}, fields...), nil
+}
+
+// serviceIndex finds notification services by title.
+type serviceIndex map[string][]librato.Service
+
+func newServiceIndex(services []librato.Service) serviceIndex {
+	index := serviceIndex{}
+	for _, s := range services {
+		index[s.Title] = append(index[s.Title], s)
+	}
+	return index
+}
+
+// lookup returns the services titled titles, failing when a title matches
+// no service or several.
+func (i serviceIndex) lookup(titles []string) ([]librato.Service, error) {
+	services := []librato.Service{}
+	for _, title := range titles {
+		found := i[title]
+		switch len(found) {
+		case 0:
+			return nil, fmt.Errorf("no service titled %q", title)
+		case 1:
+			services = append(services, found[0])
+		default:
+			return nil, fmt.Errorf("%v services are titled %q", len(found), title)
+		}
+	}
+	return services, nil
+}
+
+// fetchServiceIndex lists the account services when any of defs uses one.
+func fetchServiceIndex(defs []alertDefinition) (serviceIndex, error) {
+	for _, def := range defs {
+		if len(def.Services) > 0 {
+			services, err := client.Services.List(context.Background())
+			if err != nil {
+				return nil, err
+			}
+			return newServiceIndex(services), nil
+		}
+	}
+	return serviceIndex{}, nil
+}
+
+var unsafeFileChars = regexp.MustCompile(`[^A-Za-z0-9._-]+`)
+
+// definitionFile returns the file name export uses for an alert name.
+func definitionFile(name string) string {
+	return strings.Trim(unsafeFileChars.ReplaceAllString(name, "_"), "_.") + ".yaml"
+}
+
+// parseDefinition decodes a definition, YAML or JSON, rejecting unknown
+// fields. Documents with fields only alerts have, like an id or services
+// given as objects, are taken as alerts as returned by the API, like the ones
+// printed by show --output json, and converted. A list with a single alert is
+// taken as that alert.
+func parseDefinition(data []byte) (alertDefinition, error) {
+	var def alertDefinition
+	data, err := yaml.YAMLToJSON(data)
+	if err != nil {
+		return def, err
+	}
+	var list []json.RawMessage
+	if json.Unmarshal(data, &list) == nil {
+		if len(list) != 1 {
+			return def, fmt.Errorf("expected a single alert, found a list of %v", len(list))
+		}
+		data = list[0]
+	}
+	var fields map[string]interface{}
+	if err := yaml.Unmarshal(data, &fields); err != nil {
+		return def, err
+	}
+	var active *bool
+	if value, set := fields["active"]; set {
+		b, ok := value.(bool)
+		if !ok {
+			return def, fmt.Errorf("active must be true or false, not %v", value)
+		}
+		active = &b
+		delete(fields, "active")
+	}
+
+	if isAlertDocument(fields) {
+		var alert librato.Alert
+		if err := yaml.Unmarshal(data, &alert); err != nil {
+			return def, err
+		}
+		def = newDefinition(alert)
+	} else {
+		if data, err = json.Marshal(fields); err != nil {
+			return def, err
+		}
+		if err := yaml.UnmarshalStrict(data, &def); err != nil {
+			return def, err
+		}
+	}
+	def.active = active
+	return def, def.validate()
+}
+
+// isAlertDocument tells if the fields of a document are the ones of an
+// alert as returned by the API rather than a definition.
+func isAlertDocument(fields map[string]interface{}) bool {
+	for _, key := range librato.ReadOnlyFields {
+		if _, set := fields[key]; set {
+			return true
+		}
+	}
+	services, _ := fields["services"].([]interface{})
+	for _, service := range services {
+		if _, isObject := service.(map[string]interface{}); isObject {
+			return true
+		}
+	}
+	return false
+}
+
+// readDefinitionFile reads the definition in path.
+func readDefinitionFile(path string) (alertDefinition, error) {
+	data, err := ioutil.ReadFile(path)
+	if err != nil {
+		return alertDefinition{}, err
+	}
+	def, err := parseDefinition(data)
+	if err != nil {
+		return def, fmt.Errorf("%v: %w", path, err)
+	}
+	def.path = path
+	return def, nil
+}
+
+// readDefinitions reads the alert definitions of the files in dir, sorted
+// by file name, failing when two of them define the same alert.
+func readDefinitions(dir string) ([]alertDefinition, error) {
+	entries, err := ioutil.ReadDir(dir)
+	if err != nil {
+		return nil, err
+	}
+	var defs []alertDefinition
+	byName := map[string]string{}
+	for _, entry := range entries {
+		if entry.IsDir() || !hasString(definitionExts, strings.ToLower(filepath.Ext(entry.Name()))) {
+			continue
+		}
+		def, err := readDefinitionFile(filepath.Join(dir, entry.Name()))
+		if err != nil {
+			return nil, err
+		}
+		if other, seen := byName[def.Name]; seen {
+			return nil, fmt.Errorf("alert %v is defined in %v and %v", def.Name, other, def.path)
+		}
+		byName[def.Name] = def.path
+		defs = append(defs, def)
+	}
+	return defs, nil
+}
+
+// writeDefinition writes def as YAML to path.
+func writeDefinition(path string, def alertDefinition) error {
+	data, err := yaml.Marshal(def)
+	if err != nil {
+		return err
+	}
+	return ioutil.WriteFile(path, data, 0644)
+}
+
+// exportAlerts writes the definition of every alert to a file in the
+// directory given in args, ./alerts by default.
+func exportAlerts(args []string) {
+	dir := "alerts"
+	if len(args) > 0 {
+		dir = args[0]
+	}
+	err, alerts := getAllAlertList()
+	if err != nil {
+		fatal("Error getting alerts > ", err)
+	}
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		fatal("Error creating export dir > ", err)
+	}
+
+	sorted := append(alertList(nil), *alerts...)
+	sort.SliceStable(sorted, func(i, j int) bool { return sorted[i].ID < sorted[j].ID })
+	named := map[string]int{}
+	for _, alert := range sorted {
+		named[alert.Name]++
+	}
+	// alerts sharing a name can't be told apart by plan and apply, so they
+	// are left out instead of writing definitions the directory can't be
+	// read back with
+	exported, skipped := 0, map[string]bool{}
+	used := map[string]bool{}
+	for _, alert := range sorted {
+		if n := named[alert.Name]; n > 1 {
+			if !skipped[alert.Name] {
+				logLine(fmt.Sprintf("%v alerts are named %v, rename them to export them", n, alert.Name))
+				skipped[alert.Name] = true
+			}
+			continue
+		}
+		name := definitionFile(alert.Name)
+		if used[name] {
+			name = strings.TrimSuffix(name, ".yaml") + fmt.Sprintf("-%v.yaml", alert.ID)
+		}
+		used[name] = true
+		if err := writeDefinition(filepath.Join(dir, name), newDefinition(alert)); err != nil {
+			fatal("Error writing alert definition > ", err)
+		}
+		exported++
+	}
+	fmt.Printf("%v alerts exported to %v\n", exported, dir)
+}
@@ -0,0 +1,93 @@
+package main
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestParseDefinition(t *testing.T) {
+	condition := definitionCondition{Type: "above", MetricName: "cpu", Threshold: 90, Duration: 60, SummaryFunction: "max"}
+	yes, no := true, false
+	tests := []struct {
+		name    string
+		doc     string
+		want    alertDefinition
+		wantErr string
+	}{
+		{
+			name: "definition",
+			doc:  "name: cpu\nservices: [ops]\nconditions:\n- {type: above, metric_name: cpu, threshold: 90, duration: 60, summary_function: max}\n",
+			want: alertDefinition{Name: "cpu", Services: []string{"ops"}, Conditions: []definitionCondition{condition}},
+		},
+		{
+			name: "definition disabled",
+			doc:  "name: cpu\nactive: false\nconditions:\n- {type: above, metric_name: cpu, threshold: 90, duration: 60, summary_function: max}\n",
+			want: alertDefinition{Name: "cpu", Conditions: []definitionCondition{condition}, active: &no},
+		},
+		{
+			name: "alert with id",
+			doc:  `{"id": 3, "name": "cpu", "version": 2, "state": "ok", "conditions": [{"id": 9, "type": "above", "metric_name": "cpu", "threshold": 90, "duration": 60, "summary_function": "max"}]}`,
+			want: alertDefinition{Name: "cpu", Conditions: []definitionCondition{condition}},
+		},
+		{
+			name: "alert without id",
+			doc:  `{"name": "cpu", "conditions": [{"type": "above", "metric_name": "cpu", "threshold": 90, "duration": 60, "summary_function": "max"}], "services": [{"id": 2, "title": "ops"}], "active": true}`,
+			want: alertDefinition{Name: "cpu", Services: []string{"ops"}, Conditions: []definitionCondition{condition}, active: &yes},
+		},
+		{
+			name: "list of one alert",
+			doc:  `[{"id": 3, "name": "cpu", "active": false, "conditions": [{"type": "above", "metric_name": "cpu", "threshold": 90, "duration": 60, "summary_function": "max"}]}]`,
+			want: alertDefinition{Name: "cpu", Conditions: []definitionCondition{condition}, active: &no},
+		},
+		{
+			name:    "list of two alerts",
+			doc:     `[{"name": "a"}, {"name": "b"}]`,
+			wantErr: "found a list of 2",
+		},
+		{
+			name:    "invalid active",
+			doc:     "name: cpu\nactive: maybe\n",
+			wantErr: "active must be true or false",
+		},
+		{
+			name:    "unknown field",
+			doc:     "name: cpu\nthreshold: 90\n",
+			wantErr: `unknown field "threshold"`,
+		},
+		{
+			name:    "invalid definition",
+			doc:     "name: cpu\nconditions: []\n",
+			wantErr: "at least one condition is required",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			def, err := parseDefinition([]byte(tt.doc))
+			if tt.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Fatalf("got error %v, want %q", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !reflect.DeepEqual(def, tt.want) {
+				t.Errorf("got %+v, want %+v", def, tt.want)
+			}
+		})
+	}
+}
+
+func TestCreateActive(t *testing.T) {
+	yes, no := true, false
+	for _, tt := range []struct {
+		active *bool
+		want   bool
+	}{{nil, true}, {&yes, true}, {&no, false}} {
+		if got := (alertDefinition{active: tt.active}).createActive(); got != tt.want {
+			t.Errorf("active %v: got %v, want %v", tt.active, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,205 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"reflect"
+	"sort"
+	"strconv"
+)
+
+// fieldChange is a field whose value differs between two documents. Fields
+// missing from one of them have a nil value there.
+type fieldChange struct {
+	path          string
+	before, after interface{}
+}
+
+// missing marks a field that is not in a document.
+var missing = struct{}{}
+
+// diffFields compares the JSON encodings of before and after field by
+// field, returning the changes ordered by path.
+func diffFields(before, after interface{}) ([]fieldChange, error) {
+	a, err := jsonValue(before)
+	if err != nil {
+		return nil, err
+	}
+	b, err := jsonValue(after)
+	if err != nil {
+		return nil, err
+	}
+	var changes []fieldChange
+	diffValues("", a, b, &changes)
+	return changes, nil
+}
+
+// jsonValue returns v as decoded from its JSON encoding.
+func jsonValue(v interface{}) (interface{}, error) {
+	data, err := json.Marshal(v)
+	if err != nil {
+		return nil, err
+	}
+	var value interface{}
+	err = json.Unmarshal(data, &value)
+	return value, err
+}
+
+func diffValues(path string, a, b interface{}, changes *[]fieldChange) {
+	mapA, aIsMap := a.(map[string]interface{})
+	mapB, bIsMap := b.(map[string]interface{})
+	if aIsMap && bIsMap {
+		keys := map[string]bool{}
+		for key := range mapA {
+			keys[key] = true
+		}
+		for key := range mapB {
+			keys[key] = true
+		}
+		sorted := make([]string, 0, len(keys))
+		for key := range keys {
+			sorted = append(sorted, key)
+		}
+		sort.Strings(sorted)
+		for _, key := range sorted {
+			valueA, inA := mapA[key]
+			valueB, inB := mapB[key]
+			if !inA {
+				valueA = missing
+			}
+			if !inB {
+				valueB = missing
+			}
+			diffValues(joinPath(path, key), valueA, valueB, changes)
+		}
+		return
+	}
+
+	listA, aIsList := a.([]interface{})
+	listB, bIsList := b.([]interface{})
+	if aIsList && bIsList {
+		for i := 0; i < len(listA) || i < len(listB); i++ {
+			var valueA, valueB interface{} = missing, missing
+			if i < len(listA) {
+				valueA = listA[i]
+			}
+			if i < len(listB) {
+				valueB = listB[i]
+			}
+			diffValues(path+"["+strconv.Itoa(i)+"]", valueA, valueB, changes)
+		}
+		return
+	}
+
+	if !reflect.DeepEqual(a, b) {
+		change := fieldChange{path: path, before: a, after: b}
+		if a == missing {
+			change.before = nil
+		}
+		if b == missing {
+			change.after = nil
+		}
+		*changes = append(*changes, change)
+	}
+}
+
+func joinPath(path, key string) string {
+	if path == "" {
+		return key
+	}
+	return path + "." + key
+}
+
+// writeChanges prints changes one per line, indented by indent.
+func writeChanges(w io.Writer, changes []fieldChange, indent string) {
+	for _, change := range changes {
+		switch {
+		case change.before == nil:
+			fmt.Fprintf(w, "%v+ %v: %v\n", indent, change.path, diffValue(change.after))
+		case change.after == nil:
+			fmt.Fprintf(w, "%v- %v: %v\n", indent, change.path, diffValue(change.before))
+		default:
+			fmt.Fprintf(w, "%v~ %v: %v => %v\n", indent, change.path, diffValue(change.before), diffValue(change.after))
+		}
+	}
+}
+
+// diffValue formats a value of a change as JSON.
+func diffValue(v interface{}) string {
+	data, err := json.Marshal(v)
+	if err != nil {
+		return fmt.Sprint(v)
+	}
+	return string(data)
+}
+
+// mergeConflict is a field changed to different values in two versions of a
+// document. Fields removed in one of them have a nil value there.
+type mergeConflict struct {
+	path         string
+	ours, theirs interface{}
+}
+
+// mergeValues merges the changes made to base in ours and in theirs field by
+// field, as decoded from JSON. Fields changed in both to different values are
+// conflicts and keep the value in ours.
+func mergeValues(path string, base, ours, theirs interface{}, conflicts *[]mergeConflict) interface{} {
+	switch {
+	case reflect.DeepEqual(ours, theirs), reflect.DeepEqual(base, theirs):
+		return ours
+	case reflect.DeepEqual(base, ours):
+		return theirs
+	}
+
+	mapBase, baseIsMap := base.(map[string]interface{})
+	mapOurs, oursIsMap := ours.(map[string]interface{})
+	mapTheirs, theirsIsMap := theirs.(map[string]interface{})
+	if baseIsMap && oursIsMap && theirsIsMap {
+		merged := map[string]interface{}{}
+		for _, m := range []map[string]interface{}{mapBase, mapOurs, mapTheirs} {
+			for key := range m {
+				if _, done := merged[key]; done {
+					continue
+				}
+				value := mergeValues(joinPath(path, key), field(mapBase, key), field(mapOurs, key), field(mapTheirs, key), conflicts)
+				merged[key] = value
+			}
+		}
+		for key, value := range merged {
+			if value == missing {
+				delete(merged, key)
+			}
+		}
+		return merged
+	}
+
+	listBase, baseIsList := base.([]interface{})
+	listOurs, oursIsList := ours.([]interface{})
+	listTheirs, theirsIsList := theirs.([]interface{})
+	if baseIsList && oursIsList && theirsIsList && len(listBase) == len(listOurs) && len(listBase) == len(listTheirs) {
+		merged := make([]interface{}, len(listBase))
+		for i := range listBase {
+			merged[i] = mergeValues(path+"["+strconv.Itoa(i)+"]", listBase[i], listOurs[i], listTheirs[i], conflicts)
+		}
+		return merged
+	}
+
+	conflict := mergeConflict{path: path, ours: ours, theirs: theirs}
+	if ours == missing {
+		conflict.ours = nil
+	}
+	if theirs == missing {
+		conflict.theirs = nil
+	}
+	*conflicts = append(*conflicts, conflict)
+	return ours
+}
+
+// field returns the value of key in m, or missing.
+func field(m map[string]interface{}, key string) interface{} {
+	if value, ok := m[key]; ok {
+		return value
+	}
+	return missing
+}
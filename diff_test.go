@@ -0,0 +1,112 @@
+package main
+
+import (
+	"encoding/json"
+	"reflect"
+	"testing"
+)
+
+func TestMergeValues(t *testing.T) {
+	tests := []struct {
+		name               string
+		base, ours, theirs string
+		want               string
+		conflicts          []string
+		oursNil, theirsNil bool
+	}{
+		{
+			name: "unchanged",
+			base: `{"a": 1}`, ours: `{"a": 1}`, theirs: `{"a": 1}`,
+			want: `{"a": 1}`,
+		},
+		{
+			name: "different fields changed",
+			base: `{"a": 1, "b": 1}`, ours: `{"a": 2, "b": 1}`, theirs: `{"a": 1, "b": 3}`,
+			want: `{"a": 2, "b": 3}`,
+		},
+		{
+			name: "same change in both",
+			base: `{"a": 1}`, ours: `{"a": 2}`, theirs: `{"a": 2}`,
+			want: `{"a": 2}`,
+		},
+		{
+			name: "same field changed differently",
+			base: `{"a": 1, "b": 1}`, ours: `{"a": 2, "b": 1}`, theirs: `{"a": 3, "b": 4}`,
+			want:      `{"a": 2, "b": 4}`,
+			conflicts: []string{"a"},
+		},
+		{
+			name: "fields added and removed",
+			base: `{"a": 1, "b": 1}`, ours: `{"a": 1, "b": 1, "c": 1}`, theirs: `{"a": 1}`,
+			want: `{"a": 1, "c": 1}`,
+		},
+		{
+			name: "removed in ours, changed in theirs",
+			base: `{"a": 1}`, ours: `{}`, theirs: `{"a": 2}`,
+			want:      `{}`,
+			conflicts: []string{"a"},
+			oursNil:   true,
+		},
+		{
+			name:   "nested list elements merged",
+			base:   `{"conditions": [{"threshold": 1, "duration": 60}, {"threshold": 5}]}`,
+			ours:   `{"conditions": [{"threshold": 2, "duration": 60}, {"threshold": 5}]}`,
+			theirs: `{"conditions": [{"threshold": 1, "duration": 90}, {"threshold": 6}]}`,
+			want:   `{"conditions": [{"threshold": 2, "duration": 90}, {"threshold": 6}]}`,
+		},
+		{
+			name: "list grown in ours only",
+			base: `{"l": [1]}`, ours: `{"l": [1, 2]}`, theirs: `{"l": [1]}`,
+			want: `{"l": [1, 2]}`,
+		},
+		{
+			name: "list shrunk in theirs only",
+			base: `{"l": [1, 2]}`, ours: `{"l": [1, 2]}`, theirs: `{"l": [1]}`,
+			want: `{"l": [1]}`,
+		},
+		{
+			name: "conflicting list lengths",
+			base: `{"l": [1]}`, ours: `{"l": [1, 2]}`, theirs: `{"l": [1, 3, 4]}`,
+			want:      `{"l": [1, 2]}`,
+			conflicts: []string{"l"},
+		},
+		{
+			name: "list grown in ours, element changed in theirs",
+			base: `{"l": [1]}`, ours: `{"l": [1, 2]}`, theirs: `{"l": [5]}`,
+			want:      `{"l": [1, 2]}`,
+			conflicts: []string{"l"},
+		},
+		{
+			name: "type changed differently",
+			base: `{"a": {"b": 1}}`, ours: `{"a": [1]}`, theirs: `{"a": "x"}`,
+			want:      `{"a": [1]}`,
+			conflicts: []string{"a"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var docs [4]interface{}
+			for i, doc := range []string{tt.base, tt.ours, tt.theirs, tt.want} {
+				if err := json.Unmarshal([]byte(doc), &docs[i]); err != nil {
+					t.Fatal(err)
+				}
+			}
+			base, ours, theirs, want := docs[0], docs[1], docs[2], docs[3]
+			var conflicts []mergeConflict
+			got := mergeValues("", base, ours, theirs, &conflicts)
+			if !reflect.DeepEqual(got, want) {
+				t.Errorf("got %v, want %v", got, want)
+			}
+			var paths []string
+			for _, c := range conflicts {
+				paths = append(paths, c.path)
+				if (c.ours == nil) != tt.oursNil || (c.theirs == nil) != tt.theirsNil {
+					t.Errorf("conflict %+v, want ours nil %v and theirs nil %v", c, tt.oursNil, tt.theirsNil)
+				}
+			}
+			if !reflect.DeepEqual(paths, tt.conflicts) {
+				t.Errorf("got conflicts %v, want %v", paths, tt.conflicts)
+			}
+		})
+	}
+}
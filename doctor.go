@@ -0,0 +1,199 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	"github.com/fatih/color"
+	"github.com/theist/librato-alerts-cli/librato"
+)
+
+// check outcomes printed by doctor
+const (
+	checkOK   = "ok"
+	checkWarn = "warn"
+	checkFail = "FAIL"
+	checkSkip = "skip"
+)
+
+// doctor collects the checks run by the doctor mode.
+type doctor struct {
+	tw     *tabwriter.Writer
+	failed []interface{}
+}
+
+func (d *doctor) report(result, name, format string, args ...interface{}) {
+	colored := result
+	switch result {
+	case checkOK:
+		colored = color.HiGreenString(result)
+	case checkWarn:
+		colored = color.HiYellowString(result)
+	case checkFail:
+		colored = color.HiRedString(result)
+	}
+	fmt.Fprintf(d.tw, "  %v\t%v\t%v\n", colored, name, fmt.Sprintf(format, args...))
+}
+
+func (d *doctor) fail(name string, err error) {
+	d.failed = append(d.failed, err)
+	d.report(checkFail, name, "%v", err)
+}
+
+// runDoctor reports where the configuration comes from and checks the
+// credentials can read and update alerts. tokenErr is the error resolving
+// the API token, if any.
+func runDoctor(tokenErr error) {
+	printConfigSources()
+	fmt.Println()
+
+	fmt.Println("Checks:")
+	d := &doctor{tw: tabwriter.NewWriter(os.Stdout, 0, 4, 2, ' ', 0)}
+	defer func() {
+		d.tw.Flush()
+		if len(d.failed) > 0 {
+			os.Exit(errorExitCode(d.failed))
+		}
+	}()
+
+	if os.Getenv("LIBRATO_MAIL") == "" {
+		d.fail("mail", fmt.Errorf("LIBRATO_MAIL is not set"))
+	}
+	if tokenErr != nil {
+		d.fail("token", tokenErr)
+	} else {
+		d.report(checkOK, "token", "read from %v", tokenSource())
+	}
+	if len(d.failed) > 0 {
+		d.report(checkSkip, "read access", "needs the mail and token")
+		d.report(checkSkip, "write access", "needs the mail and token")
+		return
+	}
+
+	ctx := context.Background()
+	page, err := client.Alerts.ListPage(ctx, 0)
+	if err != nil {
+		d.fail("read access", err)
+		d.report(checkSkip, "write access", "needs read access")
+		return
+	}
+	d.report(checkOK, "read access", "%v alerts in the account", page.Query.Total)
+
+	if opts.skipWrite {
+		d.report(checkSkip, "write access", "--skip-write given")
+	} else {
+		checkWrite(ctx, d, page.Alerts)
+	}
+
+	limit, known := client.RateLimit()
+	switch {
+	case !known:
+		d.report(checkWarn, "rate limit", "the API sent no rate limit headers")
+	case limit.Limit > 0 && limit.Remaining*10 < limit.Limit:
+		d.report(checkWarn, "rate limit", "%v of %v requests left, resets in %v", limit.Remaining, limit.Limit, untilReset(limit))
+	default:
+		d.report(checkOK, "rate limit", "%v of %v requests left, resets in %v", limit.Remaining, limit.Limit, untilReset(limit))
+	}
+}
+
+// checkWrite updates one of alerts with its unchanged definition. Alerts
+// without description are avoided as updating them sets a "-" one.
+func checkWrite(ctx context.Context, d *doctor, alerts []librato.Alert) {
+	for _, alert := range alerts {
+		if alert.Description == "" {
+			continue
+		}
+		err := checkVersion(alert)
+		if err == nil {
+			err = client.Alerts.Update(ctx, &alert)
+		}
+		if err != nil {
+			d.fail("write access", err)
+			return
+		}
+		d.report(checkOK, "write access", "updated alert %v %v unchanged", alert.ID, alert.Name)
+		return
+	}
+	d.report(checkWarn, "write access", "unknown, no alert with a description to update unchanged")
+}
+
+func untilReset(limit librato.RateLimit) time.Duration {
+	if limit.Reset.IsZero() {
+		return 0
+	}
+	until := time.Until(limit.Reset).Round(time.Second)
+	if until < 0 {
+		return 0
+	}
+	return until
+}
+
+// printConfigSources lists the config sources in precedence order and
+// where each setting in effect comes from.
+func printConfigSources() {
+	tw := tabwriter.NewWriter(os.Stdout, 0, 4, 2, ' ', 0)
+	fmt.Fprintln(tw, "Config sources, first one wins:")
+	fmt.Fprintf(tw, "  flags\t%v\n", explicitFlags())
+	if opts.profile != "" {
+		fmt.Fprintf(tw, "  profile %v\tactive\n", opts.profile)
+	}
+	fmt.Fprintf(tw, "  environment\tloaded\n")
+	for _, source := range configSources {
+		state := "not found"
+		if source.found {
+			state = fmt.Sprintf("loaded, %v settings", source.vars)
+			if len(source.profiles) > 0 {
+				state += ", profiles " + strings.Join(source.profiles, ", ")
+			}
+		}
+		fmt.Fprintf(tw, "  %v\t%v\n", source.path, state)
+	}
+	tw.Flush()
+	fmt.Println()
+
+	fmt.Fprintln(tw, "Settings:")
+	fmt.Fprintf(tw, "  LIBRATO_MAIL\t%v\t%v\n", os.Getenv("LIBRATO_MAIL"), settingOrigin("LIBRATO_MAIL"))
+	for _, key := range tokenVars {
+		value := os.Getenv(key)
+		if value == "" {
+			continue
+		}
+		if key == "LIBRATO_TOKEN" {
+			value = "(hidden)"
+		}
+		fmt.Fprintf(tw, "  %v\t%v\t%v\n", key, value, settingOrigin(key))
+	}
+	apiOrigin := "default"
+	if opts.explicit["api-url"] {
+		apiOrigin = "--api-url flag"
+	} else if os.Getenv("LIBRATO_API_URL") != "" {
+		apiOrigin = settingOrigin("LIBRATO_API_URL")
+	}
+	fmt.Fprintf(tw, "  API URL\t%v\t%v\n", opts.apiURL, apiOrigin)
+	tw.Flush()
+}
+
+func settingOrigin(key string) string {
+	if origin, ok := settingOrigins[key]; ok {
+		return origin
+	}
+	return "not set"
+}
+
+// explicitFlags lists the global flags given in the command line.
+func explicitFlags() string {
+	var given []string
+	for _, name := range []string{"profile", "api-url", "max-retries", "timeout"} {
+		if opts.explicit[name] {
+			given = append(given, "--"+name)
+		}
+	}
+	if len(given) == 0 {
+		return "none given"
+	}
+	return strings.Join(given, ", ")
+}
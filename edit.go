@@ -0,0 +1,263 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"log"
+	"os"
+	"os/exec"
+	"runtime"
+
+	"github.com/theist/librato-alerts-cli/librato"
+	"sigs.k8s.io/yaml"
+)
+
+// editor returns the command editing files, from $VISUAL or $EDITOR.
+func editor() string {
+	if visual := os.Getenv("VISUAL"); visual != "" {
+		return visual
+	}
+	if runtime.GOOS == "windows" {
+		return envOr("EDITOR", "notepad")
+	}
+	return envOr("EDITOR", "vi")
+}
+
+// runEditor opens path in the editor, which may be given with arguments
+// like "code --wait".
+func runEditor(path string) error {
+	var cmd *exec.Cmd
+	if runtime.GOOS == "windows" {
+		cmd = exec.Command("cmd", "/C", editor()+" "+path)
+	} else {
+		cmd = exec.Command("sh", "-c", editor()+` "$1"`, "sh", path)
+	}
+	cmd.Stdin = os.Stdin
+	cmd.Stdout = os.Stdout
+	cmd.Stderr = os.Stderr
+	if tty := terminal(); tty != nil && tty != os.Stdin {
+		defer tty.Close()
+		cmd.Stdin = tty
+	}
+	if err := cmd.Run(); err != nil {
+		return fmt.Errorf("editor %v failed: %w", editor(), err)
+	}
+	return nil
+}
+
+// editableDoc returns the fields of alert that can be edited, as JSON or
+// YAML with --json unset.
+func editableDoc(alert librato.Alert, header string) ([]byte, error) {
+	doc, err := jsonValue(alert)
+	if err != nil {
+		return nil, err
+	}
+	fields := doc.(map[string]interface{})
+	for _, key := range librato.ReadOnlyFields {
+		delete(fields, key)
+	}
+	if opts.editJSON {
+		data, err := json.MarshalIndent(fields, "", "  ")
+		return append(data, '\n'), err
+	}
+	data, err := yaml.Marshal(fields)
+	return append([]byte(header), data...), err
+}
+
+// parseEdited returns base with the fields in the edited document, checking
+// the result is a valid alert.
+func parseEdited(base librato.Alert, data []byte) (librato.Alert, error) {
+	data, err := yaml.YAMLToJSON(data)
+	if err != nil {
+		return base, err
+	}
+	var fields map[string]interface{}
+	if err := json.Unmarshal(data, &fields); err != nil {
+		return base, fmt.Errorf("the alert must be an object: %w", err)
+	}
+	for _, key := range librato.ReadOnlyFields {
+		if _, set := fields[key]; set {
+			return base, fmt.Errorf("%v is set by the API and can't be edited", key)
+		}
+	}
+	doc, err := jsonValue(base)
+	if err != nil {
+		return base, err
+	}
+	for _, key := range librato.ReadOnlyFields {
+		fields[key] = doc.(map[string]interface{})[key]
+	}
+	if data, err = json.Marshal(fields); err != nil {
+		return base, err
+	}
+	edited := base
+	if err := edited.SetFields(data); err != nil {
+		return base, err
+	}
+	return edited, newDefinition(edited).validate()
+}
+
+// mergeEdits merges the edits made from base with the changes made to
+// current in the API meanwhile, returning current with both and the fields
+// both changed differently.
+func mergeEdits(base, edited, current librato.Alert) (librato.Alert, []mergeConflict, error) {
+	var docs [3]interface{}
+	for i, alert := range []librato.Alert{base, edited, current} {
+		var err error
+		if docs[i], err = jsonValue(alert); err != nil {
+			return current, nil, err
+		}
+	}
+	var conflicts []mergeConflict
+	merged := mergeValues("", docs[0], docs[1], docs[2], &conflicts).(map[string]interface{})
+	for _, key := range librato.ReadOnlyFields {
+		merged[key] = docs[2].(map[string]interface{})[key]
+	}
+	data, err := json.Marshal(merged)
+	if err != nil {
+		return current, nil, err
+	}
+	result := current
+	if err := result.SetFields(data); err != nil {
+		return current, nil, err
+	}
+	return result, conflicts, nil
+}
+
+// editAlert opens the alert referenced in args in the editor and updates it
+// with the changes saved. When the alert changes in the API while editing
+// it's not overwritten, the edits are merged with the new version instead.
+func editAlert(args []string) {
+	if len(args) != 1 {
+		usage("edit mode requires an alert name or id")
+	}
+	alert, err := findAlert(args[0])
+	if err != nil {
+		fatal("Error getting alert > ", err)
+	}
+	base := *alert
+
+	ext := ".yaml"
+	if opts.editJSON {
+		ext = ".json"
+	}
+	file, err := ioutil.TempFile("", "librato-alert-*"+ext)
+	if err != nil {
+		fatal("Error creating file to edit > ", err)
+	}
+	file.Close()
+	path := file.Name()
+	// giveUp exits keeping the file with the edits
+	giveUp := func() {
+		log.Printf("Alert %v not updated, your edits are kept in %v", base.Name, path)
+		os.Exit(exitError)
+	}
+
+	header := fmt.Sprintf("# alert %v, version %v\n", base.ID, base.Version)
+	doc, err := editableDoc(base, header)
+	if err == nil {
+		err = ioutil.WriteFile(path, doc, 0600)
+	}
+	if err != nil {
+		fatal("Error writing file to edit > ", err)
+	}
+
+edit:
+	for {
+		if err := runEditor(path); err != nil {
+			log.Println(err)
+			giveUp()
+		}
+		data, err := ioutil.ReadFile(path)
+		if err != nil {
+			fatal("Error reading edited alert > ", err)
+		}
+		edited, err := parseEdited(base, data)
+		if err != nil {
+			log.Printf("Invalid alert: %v", err)
+			if confirm("Edit it again?") {
+				continue
+			}
+			giveUp()
+		}
+
+		shown := false
+		for {
+			changes, err := diffFields(base, edited)
+			if err != nil {
+				fatal("Error comparing alerts > ", err)
+			}
+			if len(changes) == 0 {
+				fmt.Println("No changes, alert " + base.Name + " not updated")
+				os.Remove(path)
+				return
+			}
+
+			current, err := client.Alerts.Get(context.Background(), base.ID)
+			if err != nil {
+				log.Printf("Error getting alert %v: %v", base.Name, err)
+				giveUp()
+			}
+			if current.Version == base.Version {
+				if !shown {
+					writeChanges(os.Stdout, changes, "  ")
+				}
+				if opts.dryRun {
+					fmt.Println("dry run, nothing was updated")
+					os.Remove(path)
+					return
+				}
+				if err := client.Alerts.Update(context.Background(), &edited); err != nil {
+					log.Printf("Error updating alert %v: %v", base.Name, err)
+					giveUp()
+				}
+				fmt.Println(edited.Name + " updated")
+				os.Remove(path)
+				return
+			}
+
+			log.Printf("Alert %v changed while editing, version %v is now %v, not overwriting it", base.Name, base.Version, current.Version)
+			merged, conflicts, err := mergeEdits(base, edited, *current)
+			if err == nil && len(conflicts) == 0 {
+				err = newDefinition(merged).validate()
+			}
+			base = *current
+			if err != nil || len(conflicts) > 0 {
+				if err != nil {
+					log.Printf("Your edits can't be merged: %v", err)
+					merged = edited
+				}
+				header = fmt.Sprintf("# alert %v, version %v, merged with your edits\n", base.ID, base.Version)
+				for _, conflict := range conflicts {
+					line := fmt.Sprintf("# conflict in %v, kept yours %v, theirs is %v\n", conflict.path, diffValue(conflict.ours), diffValue(conflict.theirs))
+					header += line
+					fmt.Fprint(os.Stderr, line)
+				}
+				if !confirm("Edit the merged alert?") {
+					giveUp()
+				}
+				doc, err := editableDoc(merged, header)
+				if err == nil {
+					err = ioutil.WriteFile(path, doc, 0600)
+				}
+				if err != nil {
+					fatal("Error writing file to edit > ", err)
+				}
+				continue edit
+			}
+
+			mergedChanges, err := diffFields(base, merged)
+			if err != nil {
+				fatal("Error comparing alerts > ", err)
+			}
+			fmt.Println("Your edits merged with the new version:")
+			writeChanges(os.Stdout, mergedChanges, "  ")
+			if !confirm("Apply the merged edits?") {
+				giveUp()
+			}
+			edited, shown = merged, true
+		}
+	}
+}
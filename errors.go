@@ -0,0 +1,69 @@
+package main
+
+import (
+	"fmt"
+	"log"
+	"os"
+
+	"github.com/theist/librato-alerts-cli/librato"
+)
+
+// process exit codes, listed in the help
+const (
+	exitError       = 1
+	exitUsage       = 2
+	exitAuth        = 3
+	exitNotFound    = 4
+	exitRateLimited = 5
+	exitServer      = 6
+	exitConflict    = 7
+)
+
+// errorExitCode returns the exit code matching the first error in v.
+func errorExitCode(v []interface{}) int {
+	for _, arg := range v {
+		err, ok := arg.(error)
+		if !ok {
+			continue
+		}
+		switch {
+		case isConflict(err):
+			return exitConflict
+		case librato.IsAuth(err):
+			return exitAuth
+		case librato.IsNotFound(err):
+			return exitNotFound
+		case librato.IsRateLimited(err):
+			return exitRateLimited
+		case librato.IsServerError(err):
+			return exitServer
+		}
+	}
+	return exitError
+}
+
+// fatal is like log.Fatal, exiting with the code matching the API error
+// passed, if any.
+func fatal(v ...interface{}) {
+	log.Print(v...)
+	os.Exit(errorExitCode(v))
+}
+
+// fatalf is like log.Fatalf, exiting with the code matching the API error
+// passed, if any.
+func fatalf(format string, v ...interface{}) {
+	log.Output(2, fmt.Sprintf(format, v...))
+	os.Exit(errorExitCode(v))
+}
+
+// usage logs v and exits with the usage error code.
+func usage(v ...interface{}) {
+	log.Print(v...)
+	os.Exit(exitUsage)
+}
+
+// usagef logs a formatted message and exits with the usage error code.
+func usagef(format string, v ...interface{}) {
+	log.Output(2, fmt.Sprintf(format, v...))
+	os.Exit(exitUsage)
+}
@@ -0,0 +1,121 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"os"
+	"os/exec"
+	"os/signal"
+	"syscall"
+
+	"github.com/theist/librato-alerts-cli/librato"
+)
+
+// forwardedSignals are relayed to the child command run by exec.
+var forwardedSignals = []os.Signal{os.Interrupt, syscall.SIGTERM, syscall.SIGHUP, syscall.SIGQUIT}
+
+// alertsExec disables the selected alerts, runs the command in args and
+// enables again the alerts it disabled, whatever the command outcome. It
+// exits with the command exit code, without running it when interrupted
+// before it starts.
+func alertsExec(args []string) {
+	if len(args) == 0 {
+		usage("exec mode requires a command to run, like: librato-alerts-cli exec --disable <glob> -- ./deploy.sh")
+	}
+	targets := resolveTargets()
+	if opts.dryRun {
+		verb, _ := activeVerbs(false)
+		printPlan(activePlan(targets, false, verb))
+		return
+	}
+
+	// signals are caught before disabling anything so an early interrupt
+	// can't skip the restore
+	signals := make(chan os.Signal, 1)
+	signal.Notify(signals, forwardedSignals...)
+	defer signal.Stop(signals)
+
+	disabled := setActive(targets.alerts, false, nil)
+	if len(disabled.failed) > 0 {
+		log.Printf("%v alerts could not be disabled, running %v anyway", len(disabled.failed), args[0])
+	}
+	var code int
+	select {
+	case sig := <-signals:
+		// interrupted while disabling the alerts, the command isn't run
+		log.Printf("Got %v while disabling alerts, not running %v", sig, args[0])
+		code = exitError
+		if number, ok := sig.(syscall.Signal); ok {
+			code = 128 + int(number)
+		}
+	default:
+		code = runChild(args, signals)
+	}
+
+	fmt.Println("restoring alerts disabled for " + args[0])
+	// disabling them changed their version, so they are read again for
+	// the restore not to take that for someone else's edit
+	restore := make([]librato.Alert, 0, len(disabled.changed))
+	for _, alert := range disabled.changed {
+		if current, err := client.Alerts.Get(context.Background(), alert.ID); err == nil {
+			alert = *current
+		}
+		restore = append(restore, alert)
+	}
+	restored := setActive(restore, true, nil)
+	if len(restored.failed) > 0 {
+		log.Printf("%v alerts could not be enabled again, enable them by hand", len(restored.failed))
+		if code == 0 {
+			code = exitError
+		}
+	}
+	os.Exit(code)
+}
+
+// runChild runs args with the standard streams attached, relaying signals to
+// it, and returns its exit code. Signals the child got from the terminal too
+// aren't relayed, as commands like terraform abort on a second interrupt.
+func runChild(args []string, signals chan os.Signal) int {
+	cmd := exec.Command(args[0], args[1:]...)
+	cmd.Stdin = os.Stdin
+	cmd.Stdout = os.Stdout
+	cmd.Stderr = os.Stderr
+	if err := cmd.Start(); err != nil {
+		log.Printf("Error running %v: %v", args[0], err)
+		return 127
+	}
+
+	done := make(chan error, 1)
+	go func() {
+		done <- cmd.Wait()
+	}()
+	for {
+		select {
+		case sig := <-signals:
+			if !sentByTerminal(sig) {
+				cmd.Process.Signal(sig)
+			}
+		case err := <-done:
+			return exitCode(err)
+		}
+	}
+}
+
+// exitCode translates the error returned by exec.Cmd.Wait into a process exit
+// code, using the shell convention of 128 + signal for killed commands.
+func exitCode(err error) int {
+	if err == nil {
+		return 0
+	}
+	var exitErr *exec.ExitError
+	if !errors.As(err, &exitErr) {
+		log.Println(err)
+		return 1
+	}
+	if status, ok := exitErr.Sys().(syscall.WaitStatus); ok && status.Signaled() {
+		return 128 + int(status.Signal())
+	}
+	return exitErr.ExitCode()
+}
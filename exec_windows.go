@@ -0,0 +1,9 @@
+package main
+
+import "os"
+
+// sentByTerminal tells if sig may come from the console, which sends Ctrl-C
+// to every process attached to it, the child included.
+func sentByTerminal(sig os.Signal) bool {
+	return sig == os.Interrupt
+}
@@ -0,0 +1,240 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/theist/librato-alerts-cli/librato"
+)
+
+// options holds the settings shared by every mode. Defaults come from the
+// environment (already loaded from .env and the home config file) and are
+// overridden by command line flags.
+type options struct {
+	// explicit has the names of the flags given in the command line
+	explicit map[string]bool
+
+	profile    string
+	apiURL     string
+	maxRetries int
+	timeout    time.Duration
+	output     string
+	format     string
+
+	// alert selection for enable and disable
+	match   stringList
+	regex   stringList
+	id      stringList
+	metric  stringList
+	service stringList
+	strict  bool
+	// inputFormat is the format of alerts piped on stdin
+	inputFormat string
+
+	// dryRun prints what a mutating mode would change without changing it
+	dryRun bool
+	// parallel is the number of concurrent updates of mutating modes
+	parallel int
+
+	// snoozeFor is how long snooze keeps alerts disabled
+	snoozeFor time.Duration
+
+	// skipWrite makes doctor leave out the write access check
+	skipWrite bool
+
+	// create describes the alert built by create
+	create createFlags
+
+	// prune makes plan and apply delete the alerts without a definition
+	prune bool
+	// yes skips asking for confirmation
+	yes bool
+	// editJSON makes edit use JSON instead of YAML
+	editJSON bool
+	// all makes restore-deleted restore every alert in the trash
+	all bool
+}
+
+var opts options
+
+// stringList is a flag that can be repeated, collecting every value.
+type stringList []string
+
+func (l *stringList) String() string {
+	return strings.Join(*l, ", ")
+}
+
+func (l *stringList) Set(value string) error {
+	*l = append(*l, value)
+	return nil
+}
+
+// envOr returns the value of the environment variable key or def when unset.
+func envOr(key, def string) string {
+	if value, present := os.LookupEnv(key); present && value != "" {
+		return value
+	}
+	return def
+}
+
+// loadDefaults fills opts from the environment.
+func loadDefaults() {
+	opts.explicit = map[string]bool{}
+	opts.profile = envOr("LIBRATO_PROFILE", "")
+	loadAPIURL()
+	opts.maxRetries = librato.DefaultMaxRetries
+	opts.timeout = librato.DefaultTimeout
+	opts.output = "text"
+}
+
+// loadAPIURL sets the API endpoint from the environment.
+func loadAPIURL() {
+	opts.apiURL = envOr("LIBRATO_API_URL", librato.DefaultBaseURL)
+}
+
+// newFlagSet returns a flag set with the global flags registered. Flags are
+// registered using the current opts values as defaults so global flags can
+// be given before or after the mode.
+func newFlagSet(name string) *flag.FlagSet {
+	fs := flag.NewFlagSet(name, flag.ExitOnError)
+	fs.Usage = func() {
+		fmt.Fprintln(fs.Output(), "Usage: librato-alerts-cli [flags] [mode] [mode flags], run librato-alerts-cli help for details")
+		fs.PrintDefaults()
+	}
+	fs.StringVar(&opts.profile, "profile", opts.profile, "named profile of the config file to use (LIBRATO_PROFILE)")
+	fs.StringVar(&opts.apiURL, "api-url", opts.apiURL, "Librato API endpoint (LIBRATO_API_URL)")
+	fs.IntVar(&opts.maxRetries, "max-retries", opts.maxRetries, "times a failed API request is retried")
+	fs.DurationVar(&opts.timeout, "timeout", opts.timeout, "time limit of every API request attempt, 0 for none")
+	fs.StringVar(&opts.format, "format", opts.format, "go template rendered for every alert, overrides --output")
+	fs.StringVar(&opts.output, "output", opts.output, "output format: "+strings.Join(outputFormats, ", "))
+	return fs
+}
+
+// selectsAlerts tells if mode works on alerts piped by name or picked by
+// selection flags.
+func selectsAlerts(mode string) bool {
+	switch mode {
+	case "enable", "disable", "snooze", "exec", "delete", "set":
+		return true
+	}
+	return false
+}
+
+// mutates tells if mode changes alerts, supporting --dry-run.
+func mutates(mode string) bool {
+	switch mode {
+	case "enable", "disable", "snooze", "resume", "unsnooze-expired", "exec", "apply", "create", "delete", "restore-deleted", "set", "edit":
+		return true
+	}
+	return false
+}
+
+// updatesInBulk tells if a mode changing alerts may change many at once,
+// supporting --parallel.
+func updatesInBulk(mode string) bool {
+	switch mode {
+	case "create", "restore-deleted", "edit":
+		return false
+	}
+	return mutates(mode)
+}
+
+// addModeFlags registers the flags specific to mode.
+func addModeFlags(mode string, fs *flag.FlagSet) {
+	if selectsAlerts(mode) {
+		fs.Var(&opts.match, "match", "select alerts whose name matches a glob pattern, can be repeated")
+		fs.Var(&opts.regex, "regex", "select alerts whose name matches a regular expression, can be repeated")
+		fs.Var(&opts.id, "id", "select an alert by id, can be repeated")
+		fs.Var(&opts.metric, "metric", "select alerts with a condition on a metric, can be repeated")
+		fs.Var(&opts.service, "service", "select alerts notifying a service by title, can be repeated")
+		fs.BoolVar(&opts.strict, "strict", false, "abort if a piped name matches no alert or several")
+		fs.StringVar(&opts.inputFormat, "input-format", "auto", "format of the alerts piped on stdin: "+strings.Join(inputFormats, ", "))
+	}
+	if mutates(mode) {
+		fs.BoolVar(&opts.dryRun, "dry-run", false, "print what would change without updating anything")
+		if updatesInBulk(mode) {
+			fs.IntVar(&opts.parallel, "parallel", 4, "number of alerts updated at the same time")
+		}
+	}
+	switch mode {
+	case "exec":
+		fs.Var(&opts.match, "disable", "alerts to disable while the command runs, same as --match")
+	case "snooze":
+		fs.DurationVar(&opts.snoozeFor, "for", 0, "time to keep the alerts disabled, like 45m or 2h")
+	case "plan":
+		fs.BoolVar(&opts.prune, "prune", false, "delete the alerts without a definition")
+	case "apply":
+		fs.BoolVar(&opts.prune, "prune", false, "delete the alerts without a definition")
+		fs.BoolVar(&opts.yes, "yes", false, "apply the changes without asking for confirmation")
+	case "delete":
+		fs.BoolVar(&opts.yes, "yes", false, "delete the alerts without asking for confirmation")
+	case "restore-deleted":
+		fs.BoolVar(&opts.all, "all", false, "restore every deleted alert in the trash")
+	case "create":
+		fs.StringVar(&opts.create.name, "name", "", "name of the new alert")
+		fs.StringVar(&opts.create.description, "description", "", "description of the new alert")
+		fs.StringVar(&opts.create.metric, "metric", "", "metric the condition applies to")
+		fs.StringVar(&opts.create.condType, "type", "above", "condition type: "+strings.Join(conditionTypes, ", "))
+		fs.Float64Var(&opts.create.threshold, "threshold", 0, "value the metric must go above or below")
+		fs.DurationVar(&opts.create.duration, "duration", 0, "time the condition must hold to trigger, like 5m")
+		fs.StringVar(&opts.create.summaryFunction, "summary-function", "average", "function summarizing the metric, like average, max or sum")
+		fs.Var(&opts.create.services, "service", "title of a service to notify, can be repeated")
+		fs.DurationVar(&opts.create.rearm, "rearm", 10*time.Minute, "time before the alert can trigger again")
+	case "edit":
+		fs.BoolVar(&opts.editJSON, "json", false, "edit the alert as JSON instead of YAML")
+	case "doctor":
+		fs.BoolVar(&opts.skipWrite, "skip-write", false, "don't check write access updating an alert unchanged")
+	}
+}
+
+// parseArgs parses the global flags, the mode and the mode flags from args,
+// returning the mode and the remaining positional arguments.
+func parseArgs(args []string) (string, []string) {
+	global := newFlagSet("librato-alerts-cli")
+	global.Parse(args)
+
+	mode := "list"
+	rest := global.Args()
+	if len(rest) > 0 {
+		mode = rest[0]
+		rest = rest[1:]
+	}
+
+	modeFlags := newFlagSet(mode)
+	addModeFlags(mode, modeFlags)
+	positional := parseInterspersed(modeFlags, rest)
+
+	for _, fs := range []*flag.FlagSet{global, modeFlags} {
+		fs.Visit(func(f *flag.Flag) {
+			opts.explicit[f.Name] = true
+		})
+	}
+	return mode, positional
+}
+
+// parseInterspersed parses args allowing flags after positional arguments,
+// which are returned. Everything after -- is positional.
+func parseInterspersed(fs *flag.FlagSet, args []string) []string {
+	var tail []string
+	for i, arg := range args {
+		if arg == "--" {
+			args, tail = args[:i], args[i+1:]
+			break
+		}
+	}
+
+	var positional []string
+	for {
+		fs.Parse(args)
+		args = fs.Args()
+		if len(args) == 0 {
+			break
+		}
+		positional = append(positional, args[0])
+		args = args[1:]
+	}
+	return append(positional, tail...)
+}
@@ -5,13 +5,12 @@ go 1.17
 require (
 	github.com/fatih/color v1.13.0
 	github.com/joho/godotenv v1.4.0
+	github.com/mattn/go-isatty v0.0.14
 	github.com/mitchellh/go-homedir v1.1.0
-	gopkg.in/resty.v1 v1.12.0
+	golang.org/x/sys v0.0.0-20210630005230-0f9fa26af87c
+	sigs.k8s.io/yaml v1.4.0
 )
 
 require (
 	github.com/mattn/go-colorable v0.1.9 // indirect
-	github.com/mattn/go-isatty v0.0.14 // indirect
-	golang.org/x/net v0.0.0-20181220203305-927f97764cc3 // indirect
-	golang.org/x/sys v0.0.0-20210630005230-0f9fa26af87c // indirect
 )
@@ -0,0 +1,129 @@
+package main
+
+import (
+	"context"
+	"sort"
+	"sync"
+
+	"github.com/theist/librato-alerts-cli/librato"
+)
+
+// detailWorkers bounds the concurrent requests fetching single alerts.
+const detailWorkers = 8
+
+// alertIndex maps alert ids to alerts.
+type alertIndex map[int]librato.Alert
+
+func newAlertIndex(alerts []librato.Alert) alertIndex {
+	index := make(alertIndex, len(alerts))
+	for _, alert := range alerts {
+		index[alert.ID] = alert
+	}
+	return index
+}
+
+// lookup returns the indexed alerts with the given ids, in the same order,
+// skipping the ones not indexed.
+func (index alertIndex) lookup(ids []int) []librato.Alert {
+	alerts := make([]librato.Alert, 0, len(ids))
+	for _, id := range ids {
+		if alert, ok := index[id]; ok {
+			alerts = append(alerts, alert)
+		}
+	}
+	return alerts
+}
+
+// fetchAlertIndex returns an index holding at least the alerts with the
+// given ids. It lists every alert when that takes fewer requests than
+// fetching the missing ones one by one, which is done concurrently.
+func fetchAlertIndex(ids []int) (alertIndex, error) {
+	ctx := context.Background()
+	if len(ids) == 0 {
+		return alertIndex{}, nil
+	}
+
+	first, err := client.Alerts.ListPage(ctx, 0)
+	if err != nil {
+		return nil, err
+	}
+	index := newAlertIndex(first.Alerts)
+	var missing []int
+	for _, id := range ids {
+		if _, ok := index[id]; !ok {
+			missing = append(missing, id)
+		}
+	}
+	if len(missing) == 0 {
+		return index, nil
+	}
+
+	pending := first.Query.Total - first.Query.Offset - first.Query.Length
+	if first.Query.Length > 0 && pending > 0 {
+		pagesLeft := (pending + first.Query.Length - 1) / first.Query.Length
+		if pagesLeft <= len(missing) {
+			alerts, err := client.Alerts.List(ctx)
+			if err != nil {
+				return nil, err
+			}
+			return newAlertIndex(alerts), nil
+		}
+	}
+
+	fetched, err := getAlerts(ctx, missing)
+	if err != nil {
+		return nil, err
+	}
+	for _, alert := range fetched {
+		index[alert.ID] = alert
+	}
+	return index, nil
+}
+
+// getAlerts fetches the alerts with the given ids using up to detailWorkers
+// concurrent requests. Alerts deleted since their ids were obtained are
+// skipped.
+func getAlerts(ctx context.Context, ids []int) ([]librato.Alert, error) {
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	jobs := make(chan int)
+	var (
+		mu       sync.Mutex
+		alerts   []librato.Alert
+		firstErr error
+		wg       sync.WaitGroup
+	)
+	for i := 0; i < detailWorkers && i < len(ids); i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for id := range jobs {
+				alert, err := client.Alerts.Get(ctx, id)
+				mu.Lock()
+				switch {
+				case librato.IsNotFound(err):
+				case err != nil:
+					if firstErr == nil {
+						firstErr = err
+						cancel()
+					}
+				default:
+					alerts = append(alerts, *alert)
+				}
+				mu.Unlock()
+			}
+		}()
+	}
+	for _, id := range ids {
+		jobs <- id
+	}
+	close(jobs)
+	wg.Wait()
+
+	if firstErr != nil {
+		return nil, firstErr
+	}
+	sort.Slice(alerts, func(i, j int) bool { return alerts[i].ID < alerts[j].ID })
+	return alerts, nil
+}
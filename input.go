@@ -0,0 +1,114 @@
+package main
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"io"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// inputFormats are the accepted values for --input-format.
+var inputFormats = []string{"auto", "names", "list", "ids", "ndjson"}
+
+var (
+	// ansiEscape matches the color codes of the list and statuslist output
+	ansiEscape = regexp.MustCompile("\x1b\\[[0-9;]*[A-Za-z]")
+	// listStatus matches the status list and statuslist append to names
+	listStatus = regexp.MustCompile(`: (Active|Disabled|Firing, Active|Recent, Active)$`)
+	numericID  = regexp.MustCompile(`^[0-9]+$`)
+)
+
+// alertRef is an alert referenced in the piped input, by id or by name.
+type alertRef struct {
+	id   int
+	name string
+}
+
+func (r alertRef) String() string {
+	if r.name == "" {
+		return "id " + strconv.Itoa(r.id)
+	}
+	return r.name
+}
+
+func validInputFormat(format string) bool {
+	for _, f := range inputFormats {
+		if f == format {
+			return true
+		}
+	}
+	return false
+}
+
+// readAlertRefs reads the alerts referenced one by line in r, skipping
+// empty lines. Supported formats are:
+//
+//	names:  plain alert names
+//	list:   the output of list and statuslist, colored or not
+//	ids:    numeric alert ids
+//	ndjson: the records written by --output ndjson
+//	auto:   any of the above, detected line by line
+func readAlertRefs(r io.Reader, format string) ([]alertRef, error) {
+	var refs []alertRef
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(make([]byte, 64*1024), 16*1024*1024)
+	for lineNumber := 1; scanner.Scan(); lineNumber++ {
+		line := strings.TrimRight(scanner.Text(), "\r")
+		if strings.TrimSpace(line) == "" {
+			continue
+		}
+		ref, err := parseAlertRef(line, format)
+		if err != nil {
+			return nil, fmt.Errorf("line %v: %w", lineNumber, err)
+		}
+		refs = append(refs, ref)
+	}
+	return refs, scanner.Err()
+}
+
+func parseAlertRef(line, format string) (alertRef, error) {
+	if format == "auto" {
+		trimmed := strings.TrimSpace(ansiEscape.ReplaceAllString(line, ""))
+		switch {
+		case strings.HasPrefix(trimmed, "{"):
+			format = "ndjson"
+		case numericID.MatchString(trimmed):
+			format = "ids"
+		default:
+			format = "list"
+		}
+	}
+
+	switch format {
+	case "names":
+		return alertRef{name: line}, nil
+	case "list":
+		name := ansiEscape.ReplaceAllString(line, "")
+		return alertRef{name: listStatus.ReplaceAllString(name, "")}, nil
+	case "ids":
+		id, err := strconv.Atoi(strings.TrimSpace(ansiEscape.ReplaceAllString(line, "")))
+		if err != nil {
+			return alertRef{}, fmt.Errorf("invalid alert id %q", line)
+		}
+		return alertRef{id: id}, nil
+	case "ndjson":
+		var record struct {
+			ID   int    `json:"id"`
+			Name string `json:"name"`
+		}
+		if err := json.Unmarshal([]byte(line), &record); err != nil {
+			return alertRef{}, fmt.Errorf("invalid ndjson record: %w", err)
+		}
+		if record.ID != 0 {
+			return alertRef{id: record.ID}, nil
+		}
+		if record.Name == "" {
+			return alertRef{}, fmt.Errorf("ndjson record without id or name")
+		}
+		return alertRef{name: record.Name}, nil
+	}
+	return alertRef{}, fmt.Errorf("unknown input format %q", format)
+}
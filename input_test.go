@@ -0,0 +1,68 @@
+package main
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestParseAlertRef(t *testing.T) {
+	tests := []struct {
+		name    string
+		line    string
+		format  string
+		want    alertRef
+		wantErr bool
+	}{
+		{"plain name", "prod.db.cpu", "auto", alertRef{name: "prod.db.cpu"}, false},
+		{"list line", "prod.db.cpu: Active", "auto", alertRef{name: "prod.db.cpu"}, false},
+		{"colored list line", "prod.db.cpu: \x1b[92mActive\x1b[0m", "auto", alertRef{name: "prod.db.cpu"}, false},
+		{"colored name", "\x1b[1;31mprod.db.cpu\x1b[0m: \x1b[91mFiring, Active\x1b[0m", "list", alertRef{name: "prod.db.cpu"}, false},
+		{"recent status", "prod.db.cpu: Recent, Active", "list", alertRef{name: "prod.db.cpu"}, false},
+		{"name with colons", "svc: api: latency", "auto", alertRef{name: "svc: api: latency"}, false},
+		{"list line of name with colons", "svc: api: latency: Disabled", "auto", alertRef{name: "svc: api: latency"}, false},
+		{"status text inside name", "db: Active: replicas", "list", alertRef{name: "db: Active: replicas"}, false},
+		{"names keep status suffix", "prod.db.cpu: Active", "names", alertRef{name: "prod.db.cpu: Active"}, false},
+		{"id", "1234", "auto", alertRef{id: 1234}, false},
+		{"id with spaces", "  1234 ", "ids", alertRef{id: 1234}, false},
+		{"colored id", "\x1b[92m1234\x1b[0m", "auto", alertRef{id: 1234}, false},
+		{"invalid id", "cpu", "ids", alertRef{}, true},
+		{"ndjson id", `{"id": 12, "name": "cpu"}`, "auto", alertRef{id: 12}, false},
+		{"ndjson name", `{"name": "cpu: high"}`, "auto", alertRef{name: "cpu: high"}, false},
+		{"ndjson empty", `{}`, "ndjson", alertRef{}, true},
+		{"invalid ndjson", `{"id": `, "auto", alertRef{}, true},
+		{"unknown format", "cpu", "csv", alertRef{}, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := parseAlertRef(tt.line, tt.format)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("got error %v, want error %v", err, tt.wantErr)
+			}
+			if got != tt.want {
+				t.Errorf("got %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestReadAlertRefs(t *testing.T) {
+	input := "prod.db.cpu: Active\r\n\n  \n42\n{\"name\": \"disk\"}\n"
+	refs, err := readAlertRefs(strings.NewReader(input), "auto")
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []alertRef{{name: "prod.db.cpu"}, {id: 42}, {name: "disk"}}
+	if len(refs) != len(want) {
+		t.Fatalf("got %v, want %v", refs, want)
+	}
+	for i := range want {
+		if refs[i] != want[i] {
+			t.Errorf("ref %v: got %+v, want %+v", i, refs[i], want[i])
+		}
+	}
+
+	_, err = readAlertRefs(strings.NewReader("1\ncpu\n"), "ids")
+	if err == nil || !strings.HasPrefix(err.Error(), "line 2:") {
+		t.Errorf("got error %v, want it on line 2", err)
+	}
+}
@@ -0,0 +1,377 @@
+package librato
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"reflect"
+	"strconv"
+)
+
+// ConditionTag filters the series a condition applies to in tagged metrics
+// accounts.
+type ConditionTag struct {
+	Name    string   `json:"name"`
+	Grouped bool     `json:"grouped,omitempty"`
+	Values  []string `json:"values,omitempty"`
+}
+
+// Condition is one of the rules that trigger an alert.
+type Condition struct {
+	ID              int            `json:"id"`
+	Type            string         `json:"type"`
+	MetricName      string         `json:"metric_name"`
+	Source          string         `json:"source,omitempty"`
+	Tags            []ConditionTag `json:"tags,omitempty"`
+	Threshold       float64        `json:"threshold"`
+	Duration        int            `json:"duration"`
+	SummaryFunction string         `json:"summary_function,omitempty"`
+	DetectReset     bool           `json:"detect_reset,omitempty"`
+}
+
+// ServiceSettings holds the settings of a notification service, which
+// depend on the service type.
+type ServiceSettings map[string]interface{}
+
+// Service is a notification service attached to an alert.
+type Service struct {
+	ID       int             `json:"id"`
+	Type     string          `json:"type"`
+	Settings ServiceSettings `json:"settings"`
+	Title    string          `json:"title"`
+}
+
+// Attributes holds the free form attributes of an alert, like runbook_url.
+type Attributes map[string]interface{}
+
+// ReadOnlyFields are the alert fields set by the API, left out when
+// creating alerts.
+var ReadOnlyFields = []string{"id", "created_at", "updated_at", "version"}
+
+// Alert is a Librato alert definition.
+//
+// Alerts decoded from the API keep the document they were decoded from, so
+// updating them only replaces the fields changed since, preserving anything
+// this type does not model.
+type Alert struct {
+	ID             int         `json:"id"`
+	Name           string      `json:"name"`
+	Description    string      `json:"description"`
+	Conditions     []Condition `json:"conditions"`
+	Services       []Service   `json:"services"`
+	Attributes     Attributes  `json:"attributes"`
+	Active         bool        `json:"active"`
+	CreatedAt      int         `json:"created_at"`
+	UpdatedAt      int         `json:"updated_at"`
+	Version        int         `json:"version"`
+	RearmSeconds   int         `json:"rearm_seconds"`
+	RearmPerSignal bool        `json:"rearm_per_signal"`
+	Md             bool        `json:"md"`
+
+	// raw is the document received from the API and decoded its modeled
+	// fields as they were encoded right after decoding it
+	raw     json.RawMessage
+	decoded json.RawMessage
+}
+
+// alertFields has the Alert fields without its methods, to encode and
+// decode them without recursion.
+type alertFields Alert
+
+// UnmarshalJSON decodes an alert keeping the original document.
+func (a *Alert) UnmarshalJSON(data []byte) error {
+	if err := json.Unmarshal(data, (*alertFields)(a)); err != nil {
+		return err
+	}
+	decoded, err := json.Marshal((*alertFields)(a))
+	if err != nil {
+		return err
+	}
+	a.raw = append(json.RawMessage(nil), data...)
+	a.decoded = decoded
+	return nil
+}
+
+// Raw returns the document the alert was decoded from, nil for alerts not
+// decoded from JSON.
+func (a *Alert) Raw() json.RawMessage {
+	return a.raw
+}
+
+// SetFields replaces the modeled fields of the alert with the ones decoded
+// from doc, failing on fields the alert does not model. The document the
+// alert was decoded from is kept, so UpdateBody still preserves it.
+func (a *Alert) SetFields(doc json.RawMessage) error {
+	var fields alertFields
+	dec := json.NewDecoder(bytes.NewReader(doc))
+	dec.DisallowUnknownFields()
+	if err := dec.Decode(&fields); err != nil {
+		var typeErr *json.UnmarshalTypeError
+		if errors.As(err, &typeErr) {
+			return fmt.Errorf("%v can't be a %v", typeErr.Field, typeErr.Value)
+		}
+		return err
+	}
+	fields.raw, fields.decoded = a.raw, a.decoded
+	*a = Alert(fields)
+	return nil
+}
+
+// UpdateBody returns the JSON document to send when updating the alert: the
+// original document with only the fields changed since it was decoded
+// replaced. Changed objects and lists, like the conditions, are merged the
+// same way down to their elements. Alerts not decoded from JSON are encoded
+// as they are.
+func (a *Alert) UpdateBody() (json.RawMessage, error) {
+	current, err := json.Marshal((*alertFields)(a))
+	if err != nil || a.raw == nil {
+		return current, err
+	}
+
+	var docs [3]interface{}
+	for i, data := range []json.RawMessage{a.raw, a.decoded, current} {
+		dec := json.NewDecoder(bytes.NewReader(data))
+		dec.UseNumber()
+		if err := dec.Decode(&docs[i]); err != nil {
+			return nil, err
+		}
+	}
+	return json.Marshal(mergeChanges(docs[0], docs[1], docs[2]))
+}
+
+// mergeChanges returns raw with the changes from before to now applied.
+// Objects keep the keys before did not have, lists keep the elements
+// unknown fields when the element is still the same one.
+func mergeChanges(raw, before, now interface{}) interface{} {
+	if reflect.DeepEqual(before, now) {
+		return raw
+	}
+	switch now := now.(type) {
+	case map[string]interface{}:
+		doc, isObject := raw.(map[string]interface{})
+		old, wasObject := before.(map[string]interface{})
+		if !isObject || !wasObject {
+			return now
+		}
+		for key, value := range now {
+			orig, modeled := old[key]
+			current, present := doc[key]
+			switch {
+			case modeled && present:
+				doc[key] = mergeChanges(current, orig, value)
+			case modeled && reflect.DeepEqual(orig, value):
+				// modeled fields the document didn't have are left out
+				// unless they changed
+			default:
+				doc[key] = value
+			}
+		}
+		// fields left empty since decoding and omitted when encoding
+		for key := range old {
+			if _, ok := now[key]; !ok {
+				delete(doc, key)
+			}
+		}
+		return doc
+	case []interface{}:
+		list, isList := raw.([]interface{})
+		old, wasList := before.([]interface{})
+		if !isList || !wasList {
+			return now
+		}
+		merged := make([]interface{}, len(now))
+		for i, value := range now {
+			if i < len(old) && i < len(list) && sameElement(old[i], value) {
+				merged[i] = mergeChanges(list[i], old[i], value)
+			} else {
+				merged[i] = value
+			}
+		}
+		return merged
+	}
+	return now
+}
+
+// sameElement tells if two list elements are the same item, which for
+// objects with an id, like conditions and services, means the same id.
+func sameElement(before, now interface{}) bool {
+	old, wasObject := before.(map[string]interface{})
+	doc, isObject := now.(map[string]interface{})
+	if !wasObject || !isObject {
+		return true
+	}
+	return reflect.DeepEqual(old["id"], doc["id"])
+}
+
+// createBody returns the document to create the alert with: the one it was
+// decoded from, if any, with the changed fields replaced as in UpdateBody,
+// without the fields set by the API and referring to services by id.
+func (a *Alert) createBody() (map[string]interface{}, error) {
+	data, err := a.UpdateBody()
+	if err != nil {
+		return nil, err
+	}
+	var doc map[string]interface{}
+	dec := json.NewDecoder(bytes.NewReader(data))
+	dec.UseNumber()
+	if err := dec.Decode(&doc); err != nil {
+		return nil, err
+	}
+	for _, key := range ReadOnlyFields {
+		delete(doc, key)
+	}
+	for key, value := range doc {
+		if value == nil {
+			delete(doc, key)
+		}
+	}
+	if conditions, ok := doc["conditions"].([]interface{}); ok {
+		for _, condition := range conditions {
+			if fields, ok := condition.(map[string]interface{}); ok {
+				delete(fields, "id")
+			}
+		}
+	}
+	services := make([]int, len(a.Services))
+	for i, service := range a.Services {
+		services[i] = service.ID
+	}
+	doc["services"] = services
+	return doc, nil
+}
+
+// QueryMeta is the pagination block of list responses.
+type QueryMeta struct {
+	Offset int `json:"offset"`
+	Length int `json:"length"`
+	Found  int `json:"found"`
+	Total  int `json:"total"`
+}
+
+// AlertListResponse is a single page of alerts.
+type AlertListResponse struct {
+	Query  QueryMeta `json:"query"`
+	Alerts []Alert   `json:"alerts"`
+}
+
+// AlertEvent is an alert reported by the status endpoint.
+type AlertEvent struct {
+	ID          int `json:"id"`
+	TriggeredAt int `json:"triggered_at"`
+}
+
+// Status lists the alerts currently firing and the ones recently cleared.
+type Status struct {
+	Firing  []AlertEvent `json:"firing"`
+	Cleared []AlertEvent `json:"cleared"`
+}
+
+// AlertsService groups the /alerts endpoints.
+type AlertsService struct {
+	client *Client
+}
+
+// ListPage returns a single page of alerts starting at offset.
+func (s *AlertsService) ListPage(ctx context.Context, offset int) (*AlertListResponse, error) {
+	req, err := s.client.newRequest(ctx, http.MethodGet, "alerts?offset="+strconv.Itoa(offset), nil)
+	if err != nil {
+		return nil, err
+	}
+	var page AlertListResponse
+	if _, err := s.client.do(req, &page); err != nil {
+		return nil, err
+	}
+	return &page, nil
+}
+
+// List returns every alert in the account, following pagination.
+func (s *AlertsService) List(ctx context.Context) ([]Alert, error) {
+	var alerts []Alert
+	offset := 0
+	for {
+		page, err := s.ListPage(ctx, offset)
+		if err != nil {
+			return nil, err
+		}
+		alerts = append(alerts, page.Alerts...)
+		offset = page.Query.Offset + page.Query.Length
+		if page.Query.Length == 0 || offset >= page.Query.Total {
+			return alerts, nil
+		}
+	}
+}
+
+// Get returns the alert with the given id.
+func (s *AlertsService) Get(ctx context.Context, id int) (*Alert, error) {
+	req, err := s.client.newRequest(ctx, http.MethodGet, "alerts/"+strconv.Itoa(id), nil)
+	if err != nil {
+		return nil, err
+	}
+	var alert Alert
+	if _, err := s.client.do(req, &alert); err != nil {
+		return nil, err
+	}
+	return &alert, nil
+}
+
+// Update replaces the alert definition identified by alert.ID.
+func (s *AlertsService) Update(ctx context.Context, alert *Alert) error {
+	patched := *alert
+	// the API refuses updates with an empty description
+	if patched.Description == "" {
+		patched.Description = "-"
+	}
+	body, err := patched.UpdateBody()
+	if err != nil {
+		return err
+	}
+	req, err := s.client.newRequest(ctx, http.MethodPut, "alerts/"+strconv.Itoa(alert.ID), body)
+	if err != nil {
+		return err
+	}
+	_, err = s.client.do(req, nil)
+	return err
+}
+
+// Create creates alert, returning the alert as stored by the API.
+func (s *AlertsService) Create(ctx context.Context, alert *Alert) (*Alert, error) {
+	body, err := alert.createBody()
+	if err != nil {
+		return nil, err
+	}
+	req, err := s.client.newRequest(ctx, http.MethodPost, "alerts", body)
+	if err != nil {
+		return nil, err
+	}
+	var created Alert
+	if _, err := s.client.do(req, &created); err != nil {
+		return nil, err
+	}
+	return &created, nil
+}
+
+// Delete deletes the alert with the given id.
+func (s *AlertsService) Delete(ctx context.Context, id int) error {
+	req, err := s.client.newRequest(ctx, http.MethodDelete, "alerts/"+strconv.Itoa(id), nil)
+	if err != nil {
+		return err
+	}
+	_, err = s.client.do(req, nil)
+	return err
+}
+
+// Status returns the alerts currently firing and recently cleared.
+func (s *AlertsService) Status(ctx context.Context) (*Status, error) {
+	req, err := s.client.newRequest(ctx, http.MethodGet, "alerts/status", nil)
+	if err != nil {
+		return nil, err
+	}
+	var status Status
+	if _, err := s.client.do(req, &status); err != nil {
+		return nil, err
+	}
+	return &status, nil
+}
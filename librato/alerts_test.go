@@ -0,0 +1,312 @@
+package librato
+
+import (
+	"context"
+	"encoding/json"
+	"io/ioutil"
+	"net/http"
+	"reflect"
+	"sync"
+	"testing"
+)
+
+const testAlert = `{
+	"id": 42,
+	"name": "cpu",
+	"description": "cpu too high",
+	"conditions": [
+		{"id": 7, "type": "above", "metric_name": "cpu", "threshold": 80, "duration": 60, "summary_function": "average", "extra_cond": "kept"},
+		{"id": 8, "type": "absent", "metric_name": "cpu", "threshold": 0, "duration": 600, "extra_cond": {"nested": true}}
+	],
+	"services": [{"id": 3, "type": "mail", "settings": {"addresses": "ops@example.com"}, "title": "ops", "extra_service": 1}],
+	"attributes": {"runbook_url": "https://example.com/cpu"},
+	"active": true,
+	"created_at": 1500000000,
+	"updated_at": 1600000000,
+	"version": 2,
+	"rearm_seconds": 600,
+	"rearm_per_signal": false,
+	"md": false,
+	"extra_top": {"a": [1, 2]}
+}`
+
+// alertServer is an API storing a single alert, replaced by every PUT.
+type alertServer struct {
+	mu  sync.Mutex
+	doc []byte
+}
+
+func (s *alertServer) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	switch r.Method {
+	case http.MethodGet:
+		w.Write(s.doc)
+	case http.MethodPut:
+		s.doc, _ = ioutil.ReadAll(r.Body)
+		w.WriteHeader(http.StatusNoContent)
+	default:
+		w.WriteHeader(http.StatusMethodNotAllowed)
+	}
+}
+
+// decodeJSON decodes data as a generic document.
+func decodeJSON(t *testing.T, data []byte) map[string]interface{} {
+	t.Helper()
+	var doc map[string]interface{}
+	if err := json.Unmarshal(data, &doc); err != nil {
+		t.Fatalf("invalid JSON %s: %v", data, err)
+	}
+	return doc
+}
+
+func TestUpdateKeepsUnknownFieldsAcrossDisableAndEnable(t *testing.T) {
+	server := &alertServer{doc: []byte(testAlert)}
+	c := newTestClient(t, server)
+	ctx := context.Background()
+
+	for _, active := range []bool{false, true} {
+		alert, err := c.Alerts.Get(ctx, 42)
+		if err != nil {
+			t.Fatal(err)
+		}
+		alert.Active = active
+		if err := c.Alerts.Update(ctx, alert); err != nil {
+			t.Fatal(err)
+		}
+		if got := decodeJSON(t, server.doc)["active"]; got != active {
+			t.Errorf("stored active %v, want %v", got, active)
+		}
+	}
+
+	want := decodeJSON(t, []byte(testAlert))
+	if got := decodeJSON(t, server.doc); !reflect.DeepEqual(got, want) {
+		t.Errorf("alert changed after disabling and enabling it\ngot  %v\nwant %v", got, want)
+	}
+}
+
+func TestUpdateKeepsUnknownConditionFields(t *testing.T) {
+	server := &alertServer{doc: []byte(testAlert)}
+	c := newTestClient(t, server)
+	ctx := context.Background()
+
+	alert, err := c.Alerts.Get(ctx, 42)
+	if err != nil {
+		t.Fatal(err)
+	}
+	alert.Conditions[0].Threshold = 90
+	if err := c.Alerts.Update(ctx, alert); err != nil {
+		t.Fatal(err)
+	}
+
+	conditions := decodeJSON(t, server.doc)["conditions"].([]interface{})
+	first := conditions[0].(map[string]interface{})
+	if first["threshold"] != 90.0 {
+		t.Errorf("stored threshold %v, want 90", first["threshold"])
+	}
+	if first["extra_cond"] != "kept" {
+		t.Errorf("stored extra_cond %v, want it kept", first["extra_cond"])
+	}
+	if second := conditions[1].(map[string]interface{}); second["extra_cond"] == nil {
+		t.Errorf("unchanged condition lost extra_cond: %v", second)
+	}
+}
+
+func TestUpdateBody(t *testing.T) {
+	tests := []struct {
+		name   string
+		change func(a *Alert)
+		check  func(t *testing.T, doc map[string]interface{})
+	}{
+		{
+			name:   "unchanged",
+			change: func(a *Alert) {},
+			check: func(t *testing.T, doc map[string]interface{}) {
+				if want := decodeJSON(t, []byte(testAlert)); !reflect.DeepEqual(doc, want) {
+					t.Errorf("got %v, want the original document", doc)
+				}
+			},
+		},
+		{
+			name:   "condition removed",
+			change: func(a *Alert) { a.Conditions = a.Conditions[1:] },
+			check: func(t *testing.T, doc map[string]interface{}) {
+				conditions := doc["conditions"].([]interface{})
+				if len(conditions) != 1 {
+					t.Fatalf("got %v conditions, want 1", len(conditions))
+				}
+				// the remaining condition took another one's place
+				if c := conditions[0].(map[string]interface{}); c["id"] != 8.0 || c["extra_cond"] != nil {
+					t.Errorf("got condition %v, want condition 8 without the fields of 7", c)
+				}
+			},
+		},
+		{
+			name: "condition appended",
+			change: func(a *Alert) {
+				a.Conditions = append(a.Conditions, Condition{Type: "below", MetricName: "cpu", SummaryFunction: "min"})
+			},
+			check: func(t *testing.T, doc map[string]interface{}) {
+				conditions := doc["conditions"].([]interface{})
+				if len(conditions) != 3 {
+					t.Fatalf("got %v conditions, want 3", len(conditions))
+				}
+				if c := conditions[0].(map[string]interface{}); c["extra_cond"] != "kept" {
+					t.Errorf("first condition lost extra_cond: %v", c)
+				}
+				if c := conditions[2].(map[string]interface{}); c["type"] != "below" {
+					t.Errorf("got appended condition %v", c)
+				}
+			},
+		},
+		{
+			name:   "service replaced",
+			change: func(a *Alert) { a.Services = []Service{{ID: 4, Type: "slack", Title: "chat"}} },
+			check: func(t *testing.T, doc map[string]interface{}) {
+				service := doc["services"].([]interface{})[0].(map[string]interface{})
+				if service["id"] != 4.0 || service["extra_service"] != nil {
+					t.Errorf("got service %v, want service 4 without the fields of 3", service)
+				}
+			},
+		},
+		{
+			name:   "attribute removed",
+			change: func(a *Alert) { a.Attributes = nil },
+			check: func(t *testing.T, doc map[string]interface{}) {
+				if doc["attributes"] != nil {
+					t.Errorf("got attributes %v, want none", doc["attributes"])
+				}
+				if doc["extra_top"] == nil {
+					t.Errorf("extra_top was dropped")
+				}
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var alert Alert
+			if err := json.Unmarshal([]byte(testAlert), &alert); err != nil {
+				t.Fatal(err)
+			}
+			tt.change(&alert)
+			body, err := alert.UpdateBody()
+			if err != nil {
+				t.Fatal(err)
+			}
+			tt.check(t, decodeJSON(t, body))
+		})
+	}
+}
+
+func TestUpdateBodyWithoutRaw(t *testing.T) {
+	alert := Alert{ID: 1, Name: "new"}
+	body, err := alert.UpdateBody()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if doc := decodeJSON(t, body); doc["name"] != "new" || doc["id"] != 1.0 {
+		t.Errorf("got %v", doc)
+	}
+}
+
+func TestCreateBody(t *testing.T) {
+	var alert Alert
+	if err := json.Unmarshal([]byte(testAlert), &alert); err != nil {
+		t.Fatal(err)
+	}
+	alert.Name = "cpu copy"
+	body, err := alert.createBody()
+	if err != nil {
+		t.Fatal(err)
+	}
+	data, err := json.Marshal(body)
+	if err != nil {
+		t.Fatal(err)
+	}
+	doc := decodeJSON(t, data)
+
+	for _, key := range []string{"id", "version", "created_at", "updated_at"} {
+		if _, set := doc[key]; set {
+			t.Errorf("%v sent on create", key)
+		}
+	}
+	if doc["name"] != "cpu copy" {
+		t.Errorf("got name %v, want the changed one", doc["name"])
+	}
+	if doc["extra_top"] == nil {
+		t.Errorf("extra_top was dropped")
+	}
+	if services := doc["services"].([]interface{}); len(services) != 1 || services[0] != 3.0 {
+		t.Errorf("got services %v, want [3]", services)
+	}
+	conditions := doc["conditions"].([]interface{})
+	first := conditions[0].(map[string]interface{})
+	if _, set := first["id"]; set {
+		t.Errorf("condition id sent on create")
+	}
+	if first["extra_cond"] != "kept" {
+		t.Errorf("extra_cond was dropped: %v", first)
+	}
+}
+
+func TestCreateBodyWithoutRaw(t *testing.T) {
+	alert := Alert{
+		Name:       "new",
+		Conditions: []Condition{{ID: 5, Type: "absent", MetricName: "cpu"}},
+		Services:   []Service{{ID: 3, Title: "ops"}},
+	}
+	body, err := alert.createBody()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, set := body["attributes"]; set {
+		t.Errorf("empty attributes sent on create")
+	}
+	if fields := body["conditions"].([]interface{})[0].(map[string]interface{}); fields["id"] != nil {
+		t.Errorf("condition id sent on create")
+	}
+	if services := body["services"].([]int); len(services) != 1 || services[0] != 3 {
+		t.Errorf("got services %v, want [3]", services)
+	}
+}
+
+func TestUpdateBodyOfSparseDocument(t *testing.T) {
+	const sparse = `{"id": 5, "name": "disk", "conditions": [{"id": 1, "type": "absent", "metric_name": "disk"}], "active": true, "version": 1}`
+	tests := []struct {
+		name   string
+		change func(a *Alert)
+		want   string
+	}{
+		{
+			name:   "unchanged",
+			change: func(a *Alert) {},
+			want:   sparse,
+		},
+		{
+			name:   "disabled",
+			change: func(a *Alert) { a.Active = false },
+			want:   `{"id": 5, "name": "disk", "conditions": [{"id": 1, "type": "absent", "metric_name": "disk"}], "active": false, "version": 1}`,
+		},
+		{
+			name:   "missing field set",
+			change: func(a *Alert) { a.RearmSeconds = 60; a.Conditions[0].Duration = 300 },
+			want:   `{"id": 5, "name": "disk", "conditions": [{"id": 1, "type": "absent", "metric_name": "disk", "duration": 300}], "active": true, "version": 1, "rearm_seconds": 60}`,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var alert Alert
+			if err := json.Unmarshal([]byte(sparse), &alert); err != nil {
+				t.Fatal(err)
+			}
+			tt.change(&alert)
+			body, err := alert.UpdateBody()
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got, want := decodeJSON(t, body), decodeJSON(t, []byte(tt.want)); !reflect.DeepEqual(got, want) {
+				t.Errorf("got  %v\nwant %v", got, want)
+			}
+		})
+	}
+}
@@ -0,0 +1,164 @@
+// Package librato is a small client for the Librato alerts API.
+//
+// It covers the subset of the API used by librato-alerts-cli and is meant to
+// be importable by other tools:
+//
+//	client, err := librato.NewClient(mail, token)
+//	if err != nil {
+//		return err
+//	}
+//	alerts, err := client.Alerts.List(ctx)
+package librato
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+)
+
+// DefaultBaseURL is the Librato API endpoint used when none is configured.
+const DefaultBaseURL = "https://metrics-api.librato.com/v1"
+
+const userAgent = "librato-alerts-cli"
+
+// Client talks to the Librato API using basic auth with a user mail and an
+// API token.
+type Client struct {
+	baseURL    *url.URL
+	httpClient *http.Client
+	user       string
+	token      string
+	userAgent  string
+	maxRetries int
+	timeout    time.Duration
+	logf       func(format string, args ...interface{})
+	rate       rateLimiter
+
+	// Alerts gives access to the alert endpoints.
+	Alerts *AlertsService
+	// Services gives access to the notification service endpoints.
+	Services *ServicesService
+}
+
+// Option configures a Client created by NewClient.
+type Option func(*Client) error
+
+// WithBaseURL points the client to a different API endpoint, for example an
+// AppOptics account or an httptest server.
+func WithBaseURL(rawURL string) Option {
+	return func(c *Client) error {
+		u, err := url.Parse(strings.TrimRight(rawURL, "/") + "/")
+		if err != nil {
+			return fmt.Errorf("invalid base url %q: %w", rawURL, err)
+		}
+		if u.Scheme == "" || u.Host == "" {
+			return fmt.Errorf("invalid base url %q: scheme and host are required", rawURL)
+		}
+		c.baseURL = u
+		return nil
+	}
+}
+
+// WithHTTPClient replaces the http.Client used for every request.
+func WithHTTPClient(httpClient *http.Client) Option {
+	return func(c *Client) error {
+		c.httpClient = httpClient
+		return nil
+	}
+}
+
+// WithUserAgent sets the User-Agent header sent with every request.
+func WithUserAgent(ua string) Option {
+	return func(c *Client) error {
+		c.userAgent = ua
+		return nil
+	}
+}
+
+// NewClient returns a client authenticated with user and token.
+func NewClient(user, token string, opts ...Option) (*Client, error) {
+	c := &Client{
+		httpClient: http.DefaultClient,
+		user:       user,
+		token:      token,
+		userAgent:  userAgent,
+		maxRetries: DefaultMaxRetries,
+		timeout:    DefaultTimeout,
+	}
+	if err := WithBaseURL(DefaultBaseURL)(c); err != nil {
+		return nil, err
+	}
+	for _, opt := range opts {
+		if err := opt(c); err != nil {
+			return nil, err
+		}
+	}
+	c.Alerts = &AlertsService{client: c}
+	c.Services = &ServicesService{client: c}
+	return c, nil
+}
+
+// BaseURL returns the API endpoint the client sends requests to.
+func (c *Client) BaseURL() string {
+	return strings.TrimRight(c.baseURL.String(), "/")
+}
+
+// User returns the user the client authenticates as.
+func (c *Client) User() string {
+	return c.user
+}
+
+// newRequest builds a request for path, relative to the base URL. A non nil
+// body is encoded as JSON.
+func (c *Client) newRequest(ctx context.Context, method, path string, body interface{}) (*http.Request, error) {
+	u, err := c.baseURL.Parse(strings.TrimLeft(path, "/"))
+	if err != nil {
+		return nil, err
+	}
+
+	var reader io.Reader
+	if body != nil {
+		buf := &bytes.Buffer{}
+		if err := json.NewEncoder(buf).Encode(body); err != nil {
+			return nil, err
+		}
+		reader = buf
+	}
+
+	req, err := http.NewRequestWithContext(ctx, method, u.String(), reader)
+	if err != nil {
+		return nil, err
+	}
+	req.SetBasicAuth(c.user, c.token)
+	req.Header.Set("Accept", "application/json")
+	req.Header.Set("User-Agent", c.userAgent)
+	if body != nil {
+		req.Header.Set("Content-Type", "application/json")
+	}
+	return req, nil
+}
+
+// do sends req and decodes a JSON response body into v when v is not nil.
+// Responses outside the 2xx range are returned as *Error.
+func (c *Client) do(req *http.Request, v interface{}) (*http.Response, error) {
+	result := c.send(req)
+	resp, data := result.resp, result.data
+	if result.err != nil {
+		return resp, result.err
+	}
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		return resp, newError(req, resp, data)
+	}
+	if v != nil && len(data) > 0 {
+		if err := json.Unmarshal(data, v); err != nil {
+			return resp, fmt.Errorf("decoding %v %v response: %w", req.Method, req.URL.Path, err)
+		}
+	}
+	return resp, nil
+}
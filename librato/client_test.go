@@ -0,0 +1,146 @@
+package librato
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"testing"
+)
+
+// newTestClient returns a client sending its requests to handler.
+func newTestClient(t *testing.T, handler http.Handler) *Client {
+	t.Helper()
+	server := httptest.NewServer(handler)
+	t.Cleanup(server.Close)
+	c, err := NewClient("user@example.com", "secret", WithBaseURL(server.URL+"/v1"), WithMaxRetries(0))
+	if err != nil {
+		t.Fatal(err)
+	}
+	return c
+}
+
+func TestListFollowsPagination(t *testing.T) {
+	var offsets []string
+	c := newTestClient(t, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/v1/alerts" {
+			t.Errorf("unexpected path %v", r.URL.Path)
+		}
+		if user, token, _ := r.BasicAuth(); user != "user@example.com" || token != "secret" {
+			t.Errorf("unexpected credentials %v %v", user, token)
+		}
+		offset, _ := strconv.Atoi(r.URL.Query().Get("offset"))
+		offsets = append(offsets, r.URL.Query().Get("offset"))
+		page := AlertListResponse{Query: QueryMeta{Offset: offset, Total: 5}}
+		for id := offset + 1; id <= offset+2 && id <= 5; id++ {
+			page.Alerts = append(page.Alerts, Alert{ID: id, Name: fmt.Sprintf("alert-%v", id)})
+		}
+		page.Query.Length = len(page.Alerts)
+		json.NewEncoder(w).Encode(page)
+	}))
+
+	alerts, err := c.Alerts.List(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(alerts) != 5 {
+		t.Fatalf("got %v alerts, want 5", len(alerts))
+	}
+	for i, alert := range alerts {
+		if alert.ID != i+1 {
+			t.Errorf("alert %v has id %v, want %v", i, alert.ID, i+1)
+		}
+	}
+	if fmt.Sprint(offsets) != "[0 2 4]" {
+		t.Errorf("requested offsets %v, want [0 2 4]", offsets)
+	}
+}
+
+func TestGet(t *testing.T) {
+	c := newTestClient(t, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/v1/alerts/42":
+			fmt.Fprint(w, `{"id": 42, "name": "cpu", "version": 3, "conditions": [{"id": 7, "type": "above", "threshold": 90}]}`)
+		default:
+			w.WriteHeader(http.StatusNotFound)
+			fmt.Fprint(w, `{"errors": {"request": ["Not Found"]}}`)
+		}
+	}))
+
+	alert, err := c.Alerts.Get(context.Background(), 42)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if alert.ID != 42 || alert.Name != "cpu" || alert.Version != 3 {
+		t.Errorf("got alert %+v", alert)
+	}
+	if len(alert.Conditions) != 1 || alert.Conditions[0].Threshold != 90 {
+		t.Errorf("got conditions %+v", alert.Conditions)
+	}
+
+	_, err = c.Alerts.Get(context.Background(), 43)
+	if !IsNotFound(err) {
+		t.Errorf("got error %v, want not found", err)
+	}
+}
+
+func TestUpdate(t *testing.T) {
+	var method string
+	var body map[string]interface{}
+	c := newTestClient(t, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method == http.MethodGet {
+			fmt.Fprint(w, `{"id": 42, "name": "cpu", "description": "", "active": true}`)
+			return
+		}
+		method = r.Method
+		if r.URL.Path != "/v1/alerts/42" {
+			t.Errorf("unexpected path %v", r.URL.Path)
+		}
+		data, _ := ioutil.ReadAll(r.Body)
+		if err := json.Unmarshal(data, &body); err != nil {
+			t.Errorf("invalid body %s: %v", data, err)
+		}
+		w.WriteHeader(http.StatusNoContent)
+	}))
+
+	alert, err := c.Alerts.Get(context.Background(), 42)
+	if err != nil {
+		t.Fatal(err)
+	}
+	alert.Active = false
+	if err := c.Alerts.Update(context.Background(), alert); err != nil {
+		t.Fatal(err)
+	}
+	if method != http.MethodPut {
+		t.Errorf("sent %v, want PUT", method)
+	}
+	if body["active"] != false {
+		t.Errorf("sent active %v, want false", body["active"])
+	}
+	if body["description"] != "-" {
+		t.Errorf("sent description %q, want the - placeholder", body["description"])
+	}
+}
+
+func TestStatus(t *testing.T) {
+	c := newTestClient(t, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/v1/alerts/status" {
+			t.Errorf("unexpected path %v", r.URL.Path)
+		}
+		fmt.Fprint(w, `{"firing": [{"id": 1, "triggered_at": 1600000000}], "cleared": [{"id": 2, "triggered_at": 1500000000}]}`)
+	}))
+
+	status, err := c.Alerts.Status(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(status.Firing) != 1 || status.Firing[0].ID != 1 || status.Firing[0].TriggeredAt != 1600000000 {
+		t.Errorf("got firing %+v", status.Firing)
+	}
+	if len(status.Cleared) != 1 || status.Cleared[0].ID != 2 {
+		t.Errorf("got cleared %+v", status.Cleared)
+	}
+}
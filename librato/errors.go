@@ -0,0 +1,113 @@
+package librato
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"sort"
+	"strings"
+)
+
+// Error is an API response outside the 2xx range.
+type Error struct {
+	StatusCode int
+	Method     string
+	Path       string
+	// Messages are the errors reported in the response body, if it had the
+	// usual Librato errors document.
+	Messages []string
+	// Body is the raw response body.
+	Body string
+}
+
+func (e *Error) Error() string {
+	detail := strings.Join(e.Messages, "; ")
+	if detail == "" {
+		detail = strings.TrimSpace(e.Body)
+		if len(detail) > 200 {
+			detail = detail[:200] + "..."
+		}
+	}
+	msg := fmt.Sprintf("%v %v: %v %v", e.Method, e.Path, e.StatusCode, http.StatusText(e.StatusCode))
+	if detail != "" {
+		msg += ": " + detail
+	}
+	return msg
+}
+
+// newError builds the Error of a failed response, decoding the Librato
+// errors document, like {"errors": {"params": {"name": ["is missing"]}}}.
+func newError(req *http.Request, resp *http.Response, body []byte) *Error {
+	e := &Error{
+		StatusCode: resp.StatusCode,
+		Method:     req.Method,
+		Path:       req.URL.Path,
+		Body:       string(body),
+	}
+
+	var doc struct {
+		Errors map[string]json.RawMessage `json:"errors"`
+	}
+	if json.Unmarshal(body, &doc) != nil {
+		return e
+	}
+	kinds := make([]string, 0, len(doc.Errors))
+	for kind := range doc.Errors {
+		kinds = append(kinds, kind)
+	}
+	sort.Strings(kinds)
+	for _, kind := range kinds {
+		var list []string
+		if json.Unmarshal(doc.Errors[kind], &list) == nil {
+			for _, msg := range list {
+				e.Messages = append(e.Messages, kind+": "+msg)
+			}
+			continue
+		}
+		var fields map[string][]string
+		if json.Unmarshal(doc.Errors[kind], &fields) == nil {
+			names := make([]string, 0, len(fields))
+			for name := range fields {
+				names = append(names, name)
+			}
+			sort.Strings(names)
+			for _, name := range names {
+				for _, msg := range fields[name] {
+					e.Messages = append(e.Messages, kind+": "+name+" "+msg)
+				}
+			}
+		}
+	}
+	return e
+}
+
+// statusOf returns the status code of the API error in err chain, 0 if none.
+func statusOf(err error) int {
+	var apiErr *Error
+	if errors.As(err, &apiErr) {
+		return apiErr.StatusCode
+	}
+	return 0
+}
+
+// IsNotFound tells if err is an API not found error.
+func IsNotFound(err error) bool {
+	return statusOf(err) == http.StatusNotFound
+}
+
+// IsAuth tells if err is an API authentication or authorization error.
+func IsAuth(err error) bool {
+	code := statusOf(err)
+	return code == http.StatusUnauthorized || code == http.StatusForbidden
+}
+
+// IsRateLimited tells if err is an API rate limit error.
+func IsRateLimited(err error) bool {
+	return statusOf(err) == http.StatusTooManyRequests
+}
+
+// IsServerError tells if err is an API server side error.
+func IsServerError(err error) bool {
+	return statusOf(err) >= 500
+}
@@ -0,0 +1,98 @@
+package librato
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestNewError(t *testing.T) {
+	tests := []struct {
+		name     string
+		status   int
+		body     string
+		messages []string
+		text     string
+	}{
+		{
+			name:     "list of errors",
+			status:   http.StatusNotFound,
+			body:     `{"errors": {"request": ["Not Found", "Try again"]}}`,
+			messages: []string{"request: Not Found", "request: Try again"},
+			text:     "PUT /v1/alerts/1: 404 Not Found: request: Not Found; request: Try again",
+		},
+		{
+			name:     "errors by field",
+			status:   http.StatusBadRequest,
+			body:     `{"errors": {"params": {"name": ["is missing"], "conditions": ["is invalid", "is empty"]}}}`,
+			messages: []string{"params: conditions is invalid", "params: conditions is empty", "params: name is missing"},
+			text:     "PUT /v1/alerts/1: 400 Bad Request: params: conditions is invalid; params: conditions is empty; params: name is missing",
+		},
+		{
+			name:     "both shapes",
+			status:   http.StatusBadRequest,
+			body:     `{"errors": {"request": ["bad"], "params": {"name": ["is taken"]}}}`,
+			messages: []string{"params: name is taken", "request: bad"},
+		},
+		{
+			name:   "not a Librato document",
+			status: http.StatusBadGateway,
+			body:   "<html>bad gateway</html>",
+			text:   "PUT /v1/alerts/1: 502 Bad Gateway: <html>bad gateway</html>",
+		},
+		{
+			name:   "empty body",
+			status: http.StatusUnauthorized,
+			text:   "PUT /v1/alerts/1: 401 Unauthorized",
+		},
+		{
+			name:   "long body",
+			status: http.StatusInternalServerError,
+			body:   strings.Repeat("x", 300),
+			text:   "PUT /v1/alerts/1: 500 Internal Server Error: " + strings.Repeat("x", 200) + "...",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodPut, "/v1/alerts/1", nil)
+			resp := &http.Response{StatusCode: tt.status}
+			err := newError(req, resp, []byte(tt.body))
+			if err.StatusCode != tt.status || err.Method != http.MethodPut || err.Path != "/v1/alerts/1" {
+				t.Errorf("got %+v", err)
+			}
+			if !reflect.DeepEqual(err.Messages, tt.messages) {
+				t.Errorf("got messages %q, want %q", err.Messages, tt.messages)
+			}
+			if tt.text != "" && err.Error() != tt.text {
+				t.Errorf("got %q, want %q", err.Error(), tt.text)
+			}
+		})
+	}
+}
+
+func TestErrorPredicates(t *testing.T) {
+	tests := []struct {
+		status                                  int
+		notFound, auth, rateLimited, serverSide bool
+	}{
+		{status: http.StatusNotFound, notFound: true},
+		{status: http.StatusUnauthorized, auth: true},
+		{status: http.StatusForbidden, auth: true},
+		{status: http.StatusTooManyRequests, rateLimited: true},
+		{status: http.StatusServiceUnavailable, serverSide: true},
+		{status: http.StatusBadRequest},
+	}
+	for _, tt := range tests {
+		err := fmt.Errorf("wrapped: %w", &Error{StatusCode: tt.status})
+		got := [4]bool{IsNotFound(err), IsAuth(err), IsRateLimited(err), IsServerError(err)}
+		if want := [4]bool{tt.notFound, tt.auth, tt.rateLimited, tt.serverSide}; got != want {
+			t.Errorf("status %v: got %v, want %v", tt.status, got, want)
+		}
+	}
+	if IsNotFound(fmt.Errorf("plain")) {
+		t.Errorf("plain error taken as not found")
+	}
+}
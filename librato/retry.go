@@ -0,0 +1,243 @@
+package librato
+
+import (
+	"context"
+	"io/ioutil"
+	"math/rand"
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+)
+
+// defaults of the retry and timeout settings
+const (
+	DefaultMaxRetries = 3
+	DefaultTimeout    = 30 * time.Second
+
+	backoffBase = 500 * time.Millisecond
+	backoffMax  = 30 * time.Second
+)
+
+// RateLimit is the rate limit state reported by the last API response.
+type RateLimit struct {
+	Limit     int
+	Remaining int
+	Reset     time.Time
+}
+
+// rateLimiter tracks the rate limit headers so requests pause until the
+// limit resets instead of failing.
+type rateLimiter struct {
+	mu    sync.Mutex
+	known bool
+	limit RateLimit
+}
+
+// WithMaxRetries sets how many times a failed request is retried. Only
+// requests that are safe to repeat are retried: idempotent ones on network
+// errors and server errors, and any request rejected by the rate limit.
+func WithMaxRetries(retries int) Option {
+	return func(c *Client) error {
+		c.maxRetries = retries
+		return nil
+	}
+}
+
+// WithTimeout limits the time a single request attempt may take, zero means
+// no limit.
+func WithTimeout(timeout time.Duration) Option {
+	return func(c *Client) error {
+		c.timeout = timeout
+		return nil
+	}
+}
+
+// WithLogf sets a function used to report retries and rate limit pauses.
+func WithLogf(logf func(format string, args ...interface{})) Option {
+	return func(c *Client) error {
+		c.logf = logf
+		return nil
+	}
+}
+
+// RateLimit returns the rate limit reported by the last response, false if
+// no response carried rate limit headers yet.
+func (c *Client) RateLimit() (RateLimit, bool) {
+	c.rate.mu.Lock()
+	defer c.rate.mu.Unlock()
+	return c.rate.limit, c.rate.known
+}
+
+// attempt is the outcome of sending a request once.
+type attempt struct {
+	resp *http.Response
+	data []byte
+	err  error
+}
+
+// send sends req retrying and pausing as needed, returning the last attempt.
+func (c *Client) send(req *http.Request) attempt {
+	ctx := req.Context()
+	for try := 0; ; try++ {
+		if err := c.waitRateLimit(ctx); err != nil {
+			return attempt{err: err}
+		}
+		if try > 0 && req.GetBody != nil {
+			body, err := req.GetBody()
+			if err != nil {
+				return attempt{err: err}
+			}
+			req.Body = body
+		}
+
+		result := c.sendOnce(req)
+		if result.resp != nil {
+			c.rate.update(result.resp.Header)
+		}
+		if try >= c.maxRetries || !retryable(req, result) {
+			return result
+		}
+
+		wait := backoff(try)
+		if result.resp != nil {
+			if after, ok := retryAfter(result.resp.Header); ok {
+				wait = after
+			}
+		}
+		c.log("%v %v failed (%v), retrying in %v", req.Method, req.URL.Path, describe(result), wait.Round(time.Millisecond))
+		if err := sleep(ctx, wait); err != nil {
+			return attempt{err: err}
+		}
+	}
+}
+
+// sendOnce performs a single attempt, reading the whole body within the
+// attempt timeout.
+func (c *Client) sendOnce(req *http.Request) attempt {
+	if c.timeout > 0 {
+		ctx, cancel := context.WithTimeout(req.Context(), c.timeout)
+		defer cancel()
+		req = req.WithContext(ctx)
+	}
+	resp, err := c.httpClient.Do(req)
+	if err != nil {
+		return attempt{err: err}
+	}
+	defer resp.Body.Close()
+	data, err := ioutil.ReadAll(resp.Body)
+	return attempt{resp: resp, data: data, err: err}
+}
+
+// retryable tells if the attempt failed in a way that retrying may fix
+// without risking to repeat a non idempotent request.
+func retryable(req *http.Request, result attempt) bool {
+	if req.Context().Err() != nil {
+		return false
+	}
+	if result.resp == nil || result.err != nil {
+		return idempotent(req.Method)
+	}
+	switch result.resp.StatusCode {
+	case http.StatusTooManyRequests:
+		return true
+	case http.StatusInternalServerError, http.StatusBadGateway, http.StatusServiceUnavailable, http.StatusGatewayTimeout:
+		return idempotent(req.Method)
+	}
+	return false
+}
+
+func idempotent(method string) bool {
+	switch method {
+	case http.MethodGet, http.MethodHead, http.MethodOptions, http.MethodPut, http.MethodDelete:
+		return true
+	}
+	return false
+}
+
+func describe(result attempt) string {
+	if result.err != nil {
+		return result.err.Error()
+	}
+	return result.resp.Status
+}
+
+// backoff returns the exponential backoff for the given retry with jitter,
+// a random wait between half and the full backoff.
+func backoff(try int) time.Duration {
+	wait := backoffBase << uint(try)
+	if wait > backoffMax || wait <= 0 {
+		wait = backoffMax
+	}
+	return wait/2 + time.Duration(rand.Int63n(int64(wait/2)+1))
+}
+
+// retryAfter parses the Retry-After header, given in seconds or as a date.
+func retryAfter(header http.Header) (time.Duration, bool) {
+	value := header.Get("Retry-After")
+	if value == "" {
+		return 0, false
+	}
+	if seconds, err := strconv.Atoi(value); err == nil && seconds >= 0 {
+		return time.Duration(seconds) * time.Second, true
+	}
+	if date, err := http.ParseTime(value); err == nil {
+		wait := time.Until(date)
+		if wait < 0 {
+			wait = 0
+		}
+		return wait, true
+	}
+	return 0, false
+}
+
+// update records the rate limit headers of a response. The reset header is
+// accepted both as an unix timestamp and as seconds until the reset.
+func (r *rateLimiter) update(header http.Header) {
+	limit, errLimit := strconv.Atoi(header.Get("X-Librato-RateLimit-Limit"))
+	remaining, errRemaining := strconv.Atoi(header.Get("X-Librato-RateLimit-Remaining"))
+	reset, errReset := strconv.ParseInt(header.Get("X-Librato-RateLimit-Reset"), 10, 64)
+	if errLimit != nil || errRemaining != nil || errReset != nil {
+		return
+	}
+
+	resetAt := time.Unix(reset, 0)
+	if reset < 1000000000 {
+		resetAt = time.Now().Add(time.Duration(reset) * time.Second)
+	}
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.known = true
+	r.limit = RateLimit{Limit: limit, Remaining: remaining, Reset: resetAt}
+}
+
+// waitRateLimit pauses until the rate limit resets when it is exhausted.
+func (c *Client) waitRateLimit(ctx context.Context) error {
+	c.rate.mu.Lock()
+	exhausted := c.rate.known && c.rate.limit.Remaining <= 0
+	wait := time.Until(c.rate.limit.Reset)
+	c.rate.mu.Unlock()
+
+	if !exhausted || wait <= 0 {
+		return nil
+	}
+	c.log("rate limit reached, waiting %v until it resets", wait.Round(time.Second))
+	return sleep(ctx, wait)
+}
+
+func (c *Client) log(format string, args ...interface{}) {
+	if c.logf != nil {
+		c.logf(format, args...)
+	}
+}
+
+func sleep(ctx context.Context, d time.Duration) error {
+	timer := time.NewTimer(d)
+	defer timer.Stop()
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-timer.C:
+		return nil
+	}
+}
@@ -0,0 +1,143 @@
+package librato
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+func TestRetryAfter(t *testing.T) {
+	tests := []struct {
+		value    string
+		min, max time.Duration
+		ok       bool
+	}{
+		{value: "", ok: false},
+		{value: "0", min: 0, max: 0, ok: true},
+		{value: "5", min: 5 * time.Second, max: 5 * time.Second, ok: true},
+		{value: "-5", ok: false},
+		{value: "soon", ok: false},
+		{value: time.Now().Add(10 * time.Second).UTC().Format(http.TimeFormat), min: 8 * time.Second, max: 10 * time.Second, ok: true},
+		{value: time.Now().Add(-time.Hour).UTC().Format(http.TimeFormat), min: 0, max: 0, ok: true},
+		{value: time.Now().Add(time.Minute).UTC().Format(time.RFC850), min: 58 * time.Second, max: time.Minute, ok: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.value, func(t *testing.T) {
+			header := http.Header{}
+			if tt.value != "" {
+				header.Set("Retry-After", tt.value)
+			}
+			wait, ok := retryAfter(header)
+			if ok != tt.ok {
+				t.Fatalf("got ok %v, want %v", ok, tt.ok)
+			}
+			if ok && (wait < tt.min || wait > tt.max) {
+				t.Errorf("got %v, want between %v and %v", wait, tt.min, tt.max)
+			}
+		})
+	}
+}
+
+func TestBackoff(t *testing.T) {
+	tests := []struct {
+		try      int
+		min, max time.Duration
+	}{
+		{try: 0, min: backoffBase / 2, max: backoffBase},
+		{try: 1, min: backoffBase, max: 2 * backoffBase},
+		{try: 3, min: 4 * backoffBase, max: 8 * backoffBase},
+		{try: 10, min: backoffMax / 2, max: backoffMax},
+		{try: 100, min: backoffMax / 2, max: backoffMax},
+	}
+	for _, tt := range tests {
+		for i := 0; i < 20; i++ {
+			if wait := backoff(tt.try); wait < tt.min || wait > tt.max {
+				t.Errorf("backoff(%v) = %v, want between %v and %v", tt.try, wait, tt.min, tt.max)
+			}
+		}
+	}
+}
+
+func TestRetryable(t *testing.T) {
+	response := func(code int) attempt {
+		return attempt{resp: &http.Response{StatusCode: code}}
+	}
+	tests := []struct {
+		name   string
+		method string
+		result attempt
+		want   bool
+	}{
+		{"get network error", http.MethodGet, attempt{err: errors.New("connection reset")}, true},
+		{"post network error", http.MethodPost, attempt{err: errors.New("connection reset")}, false},
+		{"put server error", http.MethodPut, response(http.StatusServiceUnavailable), true},
+		{"post server error", http.MethodPost, response(http.StatusBadGateway), false},
+		{"post rate limited", http.MethodPost, response(http.StatusTooManyRequests), true},
+		{"delete gateway timeout", http.MethodDelete, response(http.StatusGatewayTimeout), true},
+		{"get not found", http.MethodGet, response(http.StatusNotFound), false},
+		{"get bad request", http.MethodGet, response(http.StatusBadRequest), false},
+		{"get not implemented", http.MethodGet, response(http.StatusNotImplemented), false},
+		{"get ok", http.MethodGet, response(http.StatusOK), false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest(tt.method, "/v1/alerts", nil)
+			if got := retryable(req, tt.result); got != tt.want {
+				t.Errorf("got %v, want %v", got, tt.want)
+			}
+		})
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	req := httptest.NewRequest(http.MethodGet, "/v1/alerts", nil).WithContext(ctx)
+	if retryable(req, attempt{err: ctx.Err()}) {
+		t.Errorf("retrying a canceled request")
+	}
+}
+
+func TestSendRetries(t *testing.T) {
+	tests := []struct {
+		name     string
+		method   string
+		statuses []int
+		retries  int
+		requests int
+		want     int
+	}{
+		{"get recovers", http.MethodGet, []int{503, 502, 200}, 3, 3, 200},
+		{"get gives up", http.MethodGet, []int{503, 503, 503}, 2, 3, 503},
+		{"post not repeated", http.MethodPost, []int{503, 200}, 3, 1, 503},
+		{"post rate limited", http.MethodPost, []int{429, 201}, 3, 2, 201},
+		{"no retries", http.MethodPut, []int{500, 200}, 0, 1, 500},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			requests := 0
+			c := newTestClient(t, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				status := tt.statuses[requests]
+				requests++
+				w.Header().Set("Retry-After", "0")
+				w.WriteHeader(status)
+			}))
+			c.maxRetries = tt.retries
+			req, err := c.newRequest(context.Background(), tt.method, "alerts", map[string]string{"name": "cpu"})
+			if err != nil {
+				t.Fatal(err)
+			}
+			result := c.send(req)
+			if result.err != nil {
+				t.Fatal(result.err)
+			}
+			if result.resp.StatusCode != tt.want {
+				t.Errorf("got status %v, want %v", result.resp.StatusCode, tt.want)
+			}
+			if requests != tt.requests {
+				t.Errorf("sent %v requests, want %v", requests, tt.requests)
+			}
+		})
+	}
+}
@@ -0,0 +1,49 @@
+package librato
+
+import (
+	"context"
+	"net/http"
+	"strconv"
+)
+
+// ServiceListResponse is a single page of notification services.
+type ServiceListResponse struct {
+	Query    QueryMeta `json:"query"`
+	Services []Service `json:"services"`
+}
+
+// ServicesService groups the /services endpoints.
+type ServicesService struct {
+	client *Client
+}
+
+// ListPage returns a single page of services starting at offset.
+func (s *ServicesService) ListPage(ctx context.Context, offset int) (*ServiceListResponse, error) {
+	req, err := s.client.newRequest(ctx, http.MethodGet, "services?offset="+strconv.Itoa(offset), nil)
+	if err != nil {
+		return nil, err
+	}
+	var page ServiceListResponse
+	if _, err := s.client.do(req, &page); err != nil {
+		return nil, err
+	}
+	return &page, nil
+}
+
+// List returns every notification service in the account, following
+// pagination.
+func (s *ServicesService) List(ctx context.Context) ([]Service, error) {
+	var services []Service
+	offset := 0
+	for {
+		page, err := s.ListPage(ctx, offset)
+		if err != nil {
+			return nil, err
+		}
+		services = append(services, page.Services...)
+		offset = page.Query.Offset + page.Query.Length
+		if page.Query.Length == 0 || offset >= page.Query.Total {
+			return services, nil
+		}
+	}
+}
@@ -1,110 +1,45 @@
 package main
 
 import (
-	"bufio"
-	"encoding/json"
+	"context"
 	"fmt"
 	"log"
 	"os"
-	"strconv"
 	"strings"
 
 	"github.com/fatih/color"
-	"github.com/joho/godotenv"
-	"github.com/mitchellh/go-homedir"
-	"gopkg.in/resty.v1"
+	"github.com/theist/librato-alerts-cli/librato"
 )
 
-type statusResponse struct {
-	Firing  []alertEvent `json:"firing"`
-	Cleared []alertEvent `json:"cleared"`
-}
-
-type alertEvent struct {
-	ID          int `json:"id"`
-	TriggeredAt int `json:"triggered_at"`
-}
-
-type libratoAlert struct {
-	ID          int    `json:"id"`
-	Name        string `json:"name"`
-	Description string `json:"description"`
-	Conditions  []struct {
-		ID              int     `json:"id"`
-		Type            string  `json:"type"`
-		MetricName      string  `json:"metric_name"`
-		Source          string  `json:"source"`
-		Threshold       float64 `json:"threshold"`
-		Duration        int     `json:"duration"`
-		SummaryFunction string  `json:"summary_function"`
-	} `json:"conditions"`
-	Services []struct {
-		ID       int    `json:"id"`
-		Type     string `json:"type"`
-		Settings struct {
-			URL string `json:"url"`
-		} `json:"settings"`
-		Title string `json:"title"`
-	} `json:"services"`
-	Attributes struct {
-	} `json:"attributes"`
-	Active         bool `json:"active"`
-	CreatedAt      int  `json:"created_at"`
-	UpdatedAt      int  `json:"updated_at"`
-	Version        int  `json:"version"`
-	RearmSeconds   int  `json:"rearm_seconds"`
-	RearmPerSignal bool `json:"rearm_per_signal"`
-	Md             bool `json:"md"`
-}
-
-type alertList []libratoAlert
-
-type queryMeta struct {
-	Offset int `json:"offset"`
-	Length int `json:"length"`
-	Found  int `json:"found"`
-	Total  int `json:"total"`
-}
+type alertList []librato.Alert
 
-type alertListResponse struct {
-	Query  queryMeta      `json:"query"`
-	Alerts []libratoAlert `json:"alerts"`
-}
+// client is the API client shared by every mode, set up in main.
+var client *librato.Client
 
-//TODO: firing and recent can be only one func parametrized
+// piped tells if data is being piped into the command's stdin.
+var piped bool
 
 func getAllAlertList() (error, *alertList) {
-	offset := 0
-	length := 0
-	total := 1000
-
-	var alerts alertList
-
-	for offset+length < total {
-		offset = offset + length
-		resp, err := resty.R().Get("https://metrics-api.librato.com/v1/alerts?offset=" + strconv.Itoa(offset))
-		if err != nil {
-			return err, nil
-		}
-
-		var jsonRes alertListResponse
-		err = json.Unmarshal([]byte(resp.String()), &jsonRes)
-		if err != nil {
-			return err, nil
-		}
-		length = jsonRes.Query.Length
-		offset = jsonRes.Query.Offset
-		total = jsonRes.Query.Total
-		alerts = append(alerts, jsonRes.Alerts...)
+	alerts, err := client.Alerts.List(context.Background())
+	if err != nil {
+		return err, nil
 	}
-
-	return nil, &alerts
+	list := alertList(alerts)
+	return nil, &list
 }
 
 func printAlerts() {
 	err, alerts := getAllAlertList()
 	if err != nil {
-		log.Fatal("Eror getting alert list ", err)
+		fatal("Eror getting alert list ", err)
+	}
+	if machineOutput() {
+		err, statusRes := getStatus()
+		if err != nil {
+			fatal("Error getting status: ", err)
+		}
+		printRecords(newRecords(*alerts, statusRes))
+		return
 	}
 	for _, alert := range *alerts {
 		fmt.Print(color.HiYellowString(alert.Name), ": ")
@@ -116,176 +51,137 @@ func printAlerts() {
 	}
 }
 
-func getStatus() (error, *statusResponse) {
-	resp, err := resty.R().Get("https://metrics-api.librato.com/v1/alerts/status")
+func getStatus() (error, *librato.Status) {
+	status, err := client.Alerts.Status(context.Background())
 	if err != nil {
 		return err, nil
 	}
-	log.Printf("%v\n", resp.String())
-	var jsonRes statusResponse
-	err = json.Unmarshal([]byte(resp.String()), &jsonRes)
-	if err != nil {
-		return err, nil
-	}
-	return nil, &jsonRes
+	return nil, status
 }
 
 func printFiring() {
 	err, jsonRes := getStatus()
 	if err != nil {
-		log.Fatal("Error getting firing status: ", err)
-	}
-
-	if len(jsonRes.Firing) > 0 {
-		fmt.Println("Alerts firing:")
-		for _, alert := range jsonRes.Firing {
-			resp, err := resty.R().Get("https://metrics-api.librato.com/v1/alerts/" + strconv.Itoa(alert.ID))
-			if err != nil {
-				log.Fatal("Error getting alert id > ", err)
-			}
-			var jsonAlert libratoAlert
-			err = json.Unmarshal([]byte(resp.String()), &jsonAlert)
-			if err != nil {
-				log.Fatal("Error unmarshaling Firing JSON: ", err)
-			}
-			fmt.Println(jsonAlert.Name)
-		}
-	} else {
-		fmt.Println("There are no alerts firing at this moment")
+		fatal("Error getting firing status: ", err)
 	}
+	printEvents(jsonRes, jsonRes.Firing, "Alerts firing:", "There are no alerts firing at this moment")
 }
 
 func printRecent() {
 	err, jsonRes := getStatus()
 	if err != nil {
-		log.Fatal("Error getting recent status: ", err)
+		fatal("Error getting recent status: ", err)
 	}
+	printEvents(jsonRes, jsonRes.Cleared, "Alerts recently cleared:", "There are no alerts recently cleared at this moment")
+}
 
-	if len(jsonRes.Cleared) > 0 {
-		fmt.Println("Alerts recently cleared:")
-		for _, alert := range jsonRes.Cleared {
-			resp, err := resty.R().Get("https://metrics-api.librato.com/v1/alerts/" + strconv.Itoa(alert.ID))
-			if err != nil {
-				log.Fatal("Error getting alert id > ", err)
-			}
-			var jsonAlert libratoAlert
-			err = json.Unmarshal([]byte(resp.String()), &jsonAlert)
-			if err != nil {
-				log.Fatal("Error unmarshaling Recent JSON: ", err)
-			}
-			fmt.Println(jsonAlert.Name)
+// printEvents prints the alerts of events, one of the status lists, under
+// header or the empty message when there are none.
+func printEvents(status *librato.Status, events []librato.AlertEvent, header, empty string) {
+	ids := make([]int, 0, len(events))
+	for _, event := range events {
+		ids = append(ids, event.ID)
+	}
+	index, err := fetchAlertIndex(ids)
+	if err != nil {
+		fatal("Error getting alerts > ", err)
+	}
+	alerts := index.lookup(ids)
+
+	if machineOutput() {
+		printRecords(newRecords(alerts, status))
+		return
+	}
+	if len(alerts) > 0 {
+		fmt.Println(header)
+		for _, alert := range alerts {
+			fmt.Println(alert.Name)
 		}
 	} else {
-		fmt.Println("There are no alerts recently cleared at this moment")
+		fmt.Println(empty)
 	}
 }
 
 func alertsEnable() {
-	err, alerts := getAllAlertList()
-	if err != nil {
-		log.Fatal("Eror getting alert list ", err)
-	}
+	alertsSetActive(true)
+}
 
-	scanner := bufio.NewScanner(os.Stdin)
-	for scanner.Scan() {
-		line := scanner.Text()
-		alertName := line
-		if strings.Contains(line, string(':')) {
-			arr := strings.Split(line, string(':'))
-			alertName = arr[0]
-		}
-		for _, alert := range *alerts {
-			if alert.Name == alertName {
-				if alert.Active {
-					fmt.Println("alert " + alertName + " already enabled")
-				} else {
-					fmt.Println("enabling alert " + alertName)
-					alert.Active = true
-					if alert.Description == "" {
-						alert.Description = "-"
-					}
-					result, updateErr := resty.R().
-						SetBody(alert).
-						Put("https://metrics-api.librato.com/v1/alerts/" + strconv.Itoa(alert.ID))
-					if updateErr != nil {
-						log.Fatal("Error updating alert " + alert.Name)
-					}
-					if result.IsError() {
-						log.Fatalf("Error updating alter %v: Return code (%v), Return body %v", alert.Name, result.StatusCode(), string(result.Body()))
-					}
-					fmt.Println(alert.Name + " enabled")
-				}
-			}
-		}
+func alertsDisable() {
+	alertsSetActive(false)
+}
+
+// alertsSetActive enables or disables the selected alerts, only updating the
+// ones not already in that state.
+func alertsSetActive(active bool) {
+	targets := resolveTargets()
+	verb, done := activeVerbs(active)
+	if opts.dryRun {
+		printPlan(activePlan(targets, active, verb))
+		return
 	}
+	result := setActive(targets.alerts, active, nil)
+	result.printSummary(done, "already "+done, len(targets.unmatched))
+	result.exitOnFailure()
 }
 
-func alertsDisable() {
+// resolveTargets fetches the alert list and resolves the piped names and the
+// selection flags against it.
+func resolveTargets() *selection {
 	err, alerts := getAllAlertList()
 	if err != nil {
-		log.Fatal("Eror getting alert list ", err)
+		fatal("Eror getting alert list ", err)
 	}
 
-	scanner := bufio.NewScanner(os.Stdin)
-	for scanner.Scan() {
-		line := scanner.Text()
-		alertName := line
-		if strings.Contains(line, string(':')) {
-			arr := strings.Split(line, string(':'))
-			alertName = arr[0]
-		}
-		for _, alert := range *alerts {
-			if alert.Name == alertName {
-				if alert.Active {
-					fmt.Println("disabling alert " + alert.Name)
-					alert.Active = false
-					if alert.Description == "" {
-						alert.Description = "-"
-					}
-					result, updateErr := resty.R().
-						SetBody(alert).
-						Put("https://metrics-api.librato.com/v1/alerts/" + strconv.Itoa(alert.ID))
-					if updateErr != nil {
-						log.Fatal("Error updating alert " + alert.Name)
-					}
-					if result.IsError() {
-						log.Fatalf("Error updating alter %v: Return code (%v), Return body %v", alert.Name, result.StatusCode(), string(result.Body()))
-					}
-					fmt.Println(alert.Name + " disabled")
-				} else {
-					fmt.Println("alert " + alertName + " already disabled")
-				}
-			}
+	targets, err := targetAlerts(*alerts, piped, os.Stdin)
+	if err != nil {
+		fatal("Error selecting alerts ", err)
+	}
+	if targets.report(*alerts) && opts.strict {
+		fatal("Unmatched or ambiguous alert names with --strict, nothing was changed")
+	}
+	return targets
+}
+
+// activePlan is the dry run plan of setting the selected alerts active state,
+// reporting the alerts to change with action.
+func activePlan(targets *selection, active bool, action string) plan {
+	var p plan
+	for _, alert := range targets.alerts {
+		if alert.Active == active {
+			p.add(planUnchanged, alert)
+		} else {
+			p.add(action, alert)
 		}
 	}
+	p.addNotFound(targets.unmatched)
+	return p
 }
 
 func printAlertsStatus() {
 	err, alerts := getAllAlertList()
 	if err != nil {
-		log.Fatal("Eror getting alert list ", err)
+		fatal("Eror getting alert list ", err)
 	}
 
 	err, statusRes := getStatus()
 	if err != nil {
-		log.Fatal("Error getting status: ", err)
+		fatal("Error getting status: ", err)
 	}
 
-	for _, alert := range *alerts {
+	records := newRecords(*alerts, statusRes)
+	if machineOutput() {
+		printRecords(records)
+		return
+	}
+	for _, alert := range records {
 		fmt.Print(color.HiYellowString(alert.Name), ": ")
 		if alert.Active {
 			status := color.HiGreenString("Active")
-			for _, alertStatus := range statusRes.Cleared {
-				if alert.ID == alertStatus.ID {
-					status = color.GreenString("Recent, Active")
-					break
-				}
-			}
-			for _, alertStatus := range statusRes.Firing {
-				if alert.ID == alertStatus.ID {
-					status = color.HiRedString("Firing, Active")
-					break
-				}
+			switch alert.State {
+			case stateCleared:
+				status = color.GreenString("Recent, Active")
+			case stateFiring:
+				status = color.HiRedString("Firing, Active")
 			}
 			fmt.Println(status)
 		} else {
@@ -301,7 +197,7 @@ func printHelp() {
 Small commandline client to enable and disable alerts in librato legacy
 accounts.
 
-Usage: ` + "`" + ` librato-alerts-cli [help | disable | enable | list | status | recent]` + "`" + `
+Usage: ` + "`" + ` librato-alerts-cli [flags] [help | disable | enable | list | status | recent] [flags]` + "`" + `
 
 ` + "`" + `enable` + "`" + ` and ` + "`" + `disable` + "`" + ` requires a list of alerts to disable passed by standard
 input thru a pipe, the output of ` + "`" + `list` + "`" + ` can be used for this purpose like this:
@@ -309,15 +205,137 @@ input thru a pipe, the output of ` + "`" + `list` + "`" + ` can be used for this
    librato-alerts-cli list | grep <pattern> | librato-alerts-cli disable
 ` + "```" + `
 
+or selection flags picking the alerts directly:
+` + "```" + `
+   librato-alerts-cli disable --regex '^prod\.db\.'
+` + "```" + `
+
 ## CONFIGURATION
 
 This requires two environment varables to store the librato credentials,
 ` + "`" + `LIBRATO_MAIL` + "`" + ` with the librato user's mail and ` + "`" + `LIBRATO_TOKEN` + "`" + `
 with a valid librato API token. API token must have read / write access to allow update alarms state.
+Instead of storing the token in plain text it can be read from the first line
+printed by a command set in ` + "`" + `LIBRATO_TOKEN_COMMAND` + "`" + `, like ` + "`" + `pass show librato` + "`" + `,
+or from the first line of the file set in ` + "`" + `LIBRATO_TOKEN_FILE` + "`" + `, which must not be
+readable by other users. ` + "`" + `LIBRATO_TOKEN` + "`" + ` takes precedence over the command and
+the command over the file.
 The environment variables can also be placed in an ` + "`" + `.env` + "`" + ` file or in a
 ` + "`" + `.librato-alerts-cli` + "`" + ` file in home directory. You can use ` + "`" + `librato-alerts-cli config` + "`" + `
 to generate that file.
 
+By default the tool talks to ` + "`" + `https://metrics-api.librato.com/v1` + "`" + `, set ` + "`" + `LIBRATO_API_URL` + "`" + `
+in any of those places or use the ` + "`" + `--api-url` + "`" + ` flag to point it to a different
+endpoint, like ` + "`" + `https://api.appoptics.com/v1` + "`" + `, a proxy or a local mock server.
+
+## PROFILES
+
+Several accounts can be configured as named profiles in the ` + "`" + `.env` + "`" + ` or home
+config files, placing each profile settings after a ` + "`" + `[name]` + "`" + ` line. Profiles can
+set ` + "`" + `LIBRATO_MAIL` + "`" + `, the token settings and ` + "`" + `LIBRATO_API_URL` + "`" + `, overriding the ones
+set before any section or in the environment, and are selected with ` + "`" + `--profile` + "`" + `
+or ` + "`" + `LIBRATO_PROFILE` + "`" + `. ` + "`" + `librato-alerts-cli config profiles` + "`" + ` lists them, and modes
+changing alerts print the profile in use.
+
+` + "```" + `
+   LIBRATO_MAIL=me@example.com
+   LIBRATO_TOKEN=...
+
+   [staging]
+   LIBRATO_MAIL=me@example.com
+   LIBRATO_TOKEN=...
+   LIBRATO_API_URL=https://api.appoptics.com/v1
+` + "```" + `
+
+## FLAGS
+
+` + "```" + `
+   --profile <name>:  Profile of the config files to use, overrides LIBRATO_PROFILE.
+   --api-url <url>:   API endpoint, overrides LIBRATO_API_URL.
+   --max-retries <n>: Times a failed API request is retried, 3 by default.
+                      Requests are retried with exponential backoff on network
+                      and server errors, and when the rate limit is exhausted
+                      requests wait until it resets.
+   --timeout <d>:     Time limit of every API request attempt, 30s by default.
+   --parallel <n>:    Alerts updated at the same time by modes changing alerts,
+                      4 by default. Failures don't stop the other updates, a
+                      summary is printed at the end and the exit code is not
+                      zero if any update failed.
+   --output <format>: Output of list, statuslist, status, recent and show modes, one of
+                      text (default), json, ndjson, yaml, csv or tsv. Machine
+                      readable formats include the full alert definitions and
+                      its state (firing, cleared or ok).
+   --format <tmpl>:   Go template rendered once per alert instead of --output,
+                      see TEMPLATES.
+` + "```" + `
+
+## STATE
+
+Snoozes and the backups of deleted alerts are kept in a local state directory,
+` + "`" + `~/.librato-alerts-cli.d` + "`" + ` by default, which can be changed with the
+` + "`" + `LIBRATO_STATE_DIR` + "`" + ` environment variable.
+
+## DRY RUN
+
+Modes changing alerts accept ` + "`" + `--dry-run` + "`" + `, which resolves the input and prints
+a plan with the alerts that would change, the ones already in the wanted state
+and the piped names not matching any alert, without updating anything.
+
+## CONCURRENT CHANGES
+
+Every alert is read again right before updating it. When it changed since it
+was listed, ` + "`" + `enable` + "`" + `, ` + "`" + `disable` + "`" + ` and the other modes only flipping the active flag
+update its current version, keeping the changes made meanwhile. Modes changing
+other fields, like ` + "`" + `set` + "`" + ` and ` + "`" + `apply` + "`" + `, leave it untouched and exit with a conflict.
+
+## INPUT
+
+Alerts piped into ` + "`" + `enable` + "`" + `, ` + "`" + `disable` + "`" + ` and the other modes changing alerts are
+read one by line, in the format given with ` + "`" + `--input-format` + "`" + `:
+
+` + "```" + `
+   names:  Plain alert names, taken as they are.
+   list:   The output of list or statuslist, color codes and the trailing
+           status like ": Firing, Active" are removed.
+   ids:    Numeric alert ids.
+   ndjson: Records written by --output ndjson, matched by id.
+   auto:   Any of the above, detected line by line. This is the default, use
+           names for alerts whose names look like ids or end like a status.
+` + "```" + `
+
+## SELECTION FLAGS
+
+` + "`" + `enable` + "`" + ` and ` + "`" + `disable` + "`" + ` accept these flags to pick alerts, with or without
+piped input. Each one can be repeated to match any of the values, different
+flags must all match, and when names are piped only those alerts are considered.
+An empty pipe, like the stdin of scripts and CI jobs, is ignored.
+
+` + "```" + `
+   --match <glob>:    Alert name matches a glob pattern, like 'prod.*.cpu'.
+   --regex <re>:      Alert name matches a regular expression.
+   --id <n>:          Alert id.
+   --metric <name>:   Alert has a condition on the metric.
+   --service <title>: Alert notifies the service with this title.
+   --strict:          Abort before changing anything if a piped name matches no
+                      alert or more than one. Without it those names are only
+                      reported, with suggestions for the unmatched ones.
+` + "```" + `
+
+## TEMPLATES
+
+` + "`" + `--format` + "`" + ` templates are rendered against each alert, having all the fields
+of the JSON output available with their go names, like ` + "`" + `.ID` + "`" + `, ` + "`" + `.Name` + "`" + `,
+` + "`" + `.Active` + "`" + `, ` + "`" + `.Conditions` + "`" + `, ` + "`" + `.Services` + "`" + `, ` + "`" + `.State` + "`" + ` and ` + "`" + `.TriggeredAt` + "`" + `. Literal
+` + "`" + `\t` + "`" + ` and ` + "`" + `\n` + "`" + ` are turned into tabs and newlines. Besides go template builtins
+these functions are available: ` + "`" + `join` + "`" + `, ` + "`" + `upper` + "`" + `, ` + "`" + `lower` + "`" + `, ` + "`" + `json` + "`" + `, ` + "`" + `time` + "`" + ` (unix
+timestamp as RFC3339), ` + "`" + `timefmt <layout>` + "`" + `, ` + "`" + `metrics` + "`" + ` and ` + "`" + `services` + "`" + ` (names of
+conditions metrics and services titles).
+
+` + "```" + `
+   librato-alerts-cli list --format '{{.ID}}\t{{.Name}}\t{{range .Conditions}}{{.MetricName}} {{end}}'
+   librato-alerts-cli status --format '{{.Name}} since {{time .TriggeredAt}}'
+` + "```" + `
+
 ## MODES
 
 ` + "```" + `
@@ -325,14 +343,89 @@ to generate that file.
    statuslist: List all alerts, telling if they are enabled or disabled and its status Firing / Recent.
    status:     Lists the alert names which are in alarm state.
    recent:     Lists the alert names of alert which were resolved recently.
-   enable:     Enable alerts passed by stdin. Alerts must be pased one by line,
-               and it will be updated only if they are disabled
-   disable:    Disable alerts passed by stdin. Alerts must be pased one by line,
-               and it will be updated only if they are enabled
+   show:       Shows one alert in detail, given its name or id:
+                 librato-alerts-cli show <name|id>
+   enable:     Enable alerts passed by stdin or selection flags. Alerts must be
+               pased one by line, and it will be updated only if they are disabled
+   disable:    Disable alerts passed by stdin or selection flags. Alerts must be
+               pased one by line, and it will be updated only if they are enabled
+   exec:       Disable alerts passed by stdin or selection flags, being --disable
+               <glob> a shorthand of --match, while running the command given
+               after --, enabling them again when it ends even if it fails or
+               is interrupted, and not running it when interrupted before it
+               starts. Exits with the command exit code:
+                 librato-alerts-cli exec --disable 'prod.web.*' -- ./deploy.sh
+   snooze:     Disable alerts passed by stdin or selection flags for the time
+               given with --for, like --for 45m. Only alerts enabled when
+               snoozed are enabled again by resume.
+   resume:     Enable the snoozed alerts whose time expired, meant to be run
+               periodically. Also available as unsnooze-expired.
+   snoozed:    Lists the snoozed alerts and when they expire.
+   edit:       Opens an alert, given its name or id, as YAML in $VISUAL or
+               $EDITOR, or as JSON with --json, and once saved checks it, shows
+               the changes and updates the alert. If the alert changed while
+               editing it is not overwritten, your edits are merged with the new
+               version and fields changed in both are opened again to resolve:
+                 librato-alerts-cli edit prod.db.cpu
+   set:        Changes fields of the alerts passed by stdin or selection flags,
+               given as field=value arguments. Fields are named as in the JSON
+               output, indexing lists like conditions[0]. Values are taken as
+               JSON, or as text for text fields. --dry-run shows the changes of
+               every alert. Alerts changed by someone else since they were read
+               are not updated:
+                 librato-alerts-cli set --match 'prod.*' rearm_seconds=3600 'conditions[0].threshold=95'
+   delete:     Deletes alerts passed by stdin or selection flags after listing
+               them and asking for confirmation, skipped with --yes. A JSON
+               backup of each alert is written to the trash dir in the state
+               directory before deleting it.
+   restore-deleted:
+               Creates again deleted alerts, given by id or name, from their
+               backup, or every one with --all. Without arguments lists the
+               alerts in the trash. Restored alerts get a new id.
+   create:     Creates an alert and prints its id. The alert is described with
+               --name, --metric, --type above|below|absent, --threshold,
+               --duration, --summary-function, --service (by title, can be
+               repeated), --rearm and --description, or given as a YAML or JSON
+               file in the format written by export or printed by show, - to
+               read it from stdin. New alerts are active unless the file sets
+               active to false. The alert is checked before sending it:
+                 librato-alerts-cli create --name prod.cpu --metric cpu --threshold 90 --duration 5m
+                 librato-alerts-cli create alerts/prod.cpu.yaml
+   export:     Writes the definition of every alert to a YAML file in the given
+               directory, ./alerts by default, referring to services by title.
+               Whether alerts are active is not exported. Alerts sharing a name
+               are left out, as plan and apply can't tell them apart:
+                 librato-alerts-cli export alerts/
+   plan:       Shows the field level changes needed for the alerts to match the
+               definitions in the given directory, ./alerts by default. Alerts
+               are matched by name. With --prune alerts without a definition
+               are deleted.
+   apply:      Shows the same changes as plan and, once confirmed or given
+               --yes, creates, updates and with --prune deletes alerts, backing
+               up the deleted ones to the trash like delete.
+   doctor:     Checks the setup: lists the config sources loaded and where each
+               setting comes from, checks the credentials can read alerts and
+               update them, updating one alert unchanged unless --skip-write is
+               given, and prints the rate limit left.
    config:     Prints current config in a valid format to be a proper config file.
+               ` + "`" + `config profiles` + "`" + ` lists the profiles, marking the active one.
    help:       This help.
 ` + "```" + `
 
+## EXIT CODES
+
+` + "```" + `
+   0: Success.
+   1: Generic error.
+   2: Wrong usage, like unknown flags or missing arguments.
+   3: Authentication failure, the API rejected the credentials (401 / 403).
+   4: Not found, the API has no such alert (404).
+   5: Rate limited, the API kept rejecting requests after retrying (429).
+   6: Server error, the API kept failing after retrying (5xx).
+   7: Conflict, an alert changed in the API since it was read and was not
+      overwritten.
+` + "```" + `
+
 ## ALMOST KNOWN BUGS or TODO's:
 
  * This is tested against an old, no tagged metrics librato account may work
@@ -340,7 +433,7 @@ to generate that file.
 }
 
 func checkEnv() bool {
-	envNeeded := []string{"LIBRATO_MAIL", "LIBRATO_TOKEN"}
+	envNeeded := []string{"LIBRATO_MAIL"}
 	checkEnv := true
 	for _, envVar := range envNeeded {
 		_, present := os.LookupEnv(envVar)
@@ -349,48 +442,101 @@ func checkEnv() bool {
 			log.Println("Missing needed environment variable ", envVar)
 		}
 	}
+	if !hasTokenSource() {
+		checkEnv = false
+		log.Println("Missing needed environment variable ", strings.Join(tokenVars, " or "))
+	}
 	return checkEnv
 }
 
 func printConfig() {
-	userConfigFile, _ := homedir.Expand("~/.librato-alerts-cli")
+	userConfigFile := userConfigPath()
 	fmt.Printf("# place and fill if needed these lines in a local file called .env\n")
 	fmt.Printf("# or in your home dir as %v\n", userConfigFile)
 	fmt.Printf("# or find a way to set it as environment variables\n")
 	fmt.Printf("# local .env takes precedence over home file, any of these will override already setted environment variables\n\n")
 	fmt.Printf("LIBRATO_MAIL=%v\n", os.Getenv("LIBRATO_MAIL"))
 	fmt.Printf("LIBRATO_TOKEN=%v\n", os.Getenv("LIBRATO_TOKEN"))
+	fmt.Printf("# instead of LIBRATO_TOKEN the token can be the first line printed by a\n")
+	fmt.Printf("# command or the first line of a file only readable by its owner\n")
+	fmt.Printf("LIBRATO_TOKEN_COMMAND=%v\n", os.Getenv("LIBRATO_TOKEN_COMMAND"))
+	fmt.Printf("LIBRATO_TOKEN_FILE=%v\n", os.Getenv("LIBRATO_TOKEN_FILE"))
+	fmt.Printf("# optional, defaults to %v\n", librato.DefaultBaseURL)
+	fmt.Printf("LIBRATO_API_URL=%v\n", opts.apiURL)
+	fmt.Printf("\n# more accounts can be added as named profiles, selected with --profile\n")
+	fmt.Printf("# or LIBRATO_PROFILE, placing their settings after a [name] line:\n")
+	fmt.Printf("# [staging]\n")
+	fmt.Printf("# LIBRATO_MAIL=\n")
+	fmt.Printf("# LIBRATO_TOKEN=\n")
+	fmt.Printf("# LIBRATO_API_URL=\n")
 }
 
 func main() {
-	// load dotenv
-	godotenv.Load()
-	userConfigFile, _ := homedir.Expand("~/.librato-alerts-cli")
-	godotenv.Load(userConfigFile)
-
-	// check arg 0
-	mode := "list"
-	if len(os.Args) > 1 {
-		mode = os.Args[1]
-	}
-	if mode != "config" && mode != "help" && !checkEnv() {
-		log.Fatal("Insufficient configuration. Please run librato-alerts-cli config and follow instructions")
-	}
-	// resty configuration
-	resty.SetDebug(false)
-	resty.SetBasicAuth(os.Getenv("LIBRATO_MAIL"), os.Getenv("LIBRATO_TOKEN"))
+	// load dotenv and home config file
+	profiles, err := loadConfig()
+	if err != nil {
+		fatal("Error reading config > ", err)
+	}
+
+	// flags and mode
+	loadDefaults()
+	mode, args := parseArgs(os.Args[1:])
+	if err := activateProfile(profiles); err != nil {
+		usage(err)
+	}
+	if !validOutput(opts.output) {
+		usagef("Unknown output format %v, valid ones are %v", opts.output, strings.Join(outputFormats, ", "))
+	}
+	if opts.format != "" {
+		var err error
+		outputTemplate, err = parseFormat(opts.format)
+		if err != nil {
+			usage("Invalid --format template > ", err)
+		}
+	}
+	if mode != "config" && mode != "help" && mode != "doctor" && !checkEnv() {
+		fatal("Insufficient configuration. Please run librato-alerts-cli config and follow instructions")
+	}
+	// api client
+	token, tokenErr := "", error(nil)
+	if mode != "config" && mode != "help" {
+		token, tokenErr = resolveToken()
+		if tokenErr != nil && mode != "doctor" {
+			fatal("Unable to get the API token > ", tokenErr)
+		}
+	}
+	client, err = librato.NewClient(os.Getenv("LIBRATO_MAIL"), token,
+		librato.WithBaseURL(opts.apiURL),
+		librato.WithMaxRetries(opts.maxRetries),
+		librato.WithTimeout(opts.timeout),
+		librato.WithLogf(log.Printf))
+	if err != nil {
+		fatal("Unable to set up librato client > ", err)
+	}
+	if mutates(mode) {
+		color.New(color.FgHiCyan).Fprintln(os.Stderr, "using "+profileLabel())
+	}
 	// check stdin
 	fi, err := os.Stdin.Stat()
 	if err != nil {
-		log.Fatal("Unable to read stdin >", err)
+		fatal("Unable to read stdin >", err)
 	}
 
-	piped := (fi.Mode() & os.ModeCharDevice) == 0
-	if piped && (mode == "list" || mode == "statuslist" || mode == "status" || mode == "recent") {
-		log.Fatal(mode, " mode can't be called with piped data, please use enable or disable mode")
+	piped = (fi.Mode() & os.ModeCharDevice) == 0
+	if piped && (mode == "list" || mode == "statuslist" || mode == "status" || mode == "recent" || mode == "show") {
+		usage(mode, " mode can't be called with piped data, please use enable or disable mode")
 	}
-	if !piped && (mode == "enabled" || mode == "disable") {
-		log.Fatal(mode + " mode requires a list of alerts piped into comand")
+	if selectsAlerts(mode) {
+		if !validInputFormat(opts.inputFormat) {
+			usagef("Unknown input format %v, valid ones are %v", opts.inputFormat, strings.Join(inputFormats, ", "))
+		}
+		sel, err := newSelector()
+		if err != nil {
+			usage(err)
+		}
+		if !piped && sel.empty() {
+			usage(mode + " mode requires a list of alerts piped into comand or selection flags")
+		}
 	}
 
 	switch mode {
@@ -402,12 +548,44 @@ func main() {
 		alertsEnable()
 	case "disable":
 		alertsDisable()
+	case "show":
+		showAlert(args)
+	case "exec":
+		alertsExec(args)
+	case "snooze":
+		alertsSnooze()
+	case "resume", "unsnooze-expired":
+		alertsResume()
+	case "snoozed":
+		printSnoozed()
 	case "recent":
 		printRecent()
 	case "status":
 		printFiring()
+	case "edit":
+		editAlert(args)
+	case "set":
+		alertsSet(args)
+	case "delete":
+		alertsDelete()
+	case "restore-deleted":
+		restoreDeleted(args)
+	case "create":
+		createAlert(args)
+	case "export":
+		exportAlerts(args)
+	case "plan":
+		planAlerts(args)
+	case "apply":
+		applyAlerts(args)
+	case "doctor":
+		runDoctor(tokenErr)
 	case "config":
-		printConfig()
+		if len(args) > 0 && args[0] == "profiles" {
+			printProfiles(profiles)
+		} else {
+			printConfig()
+		}
 	default:
 		printHelp()
 	}
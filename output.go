@@ -0,0 +1,221 @@
+package main
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"strconv"
+	"strings"
+	"text/template"
+	"time"
+
+	"github.com/theist/librato-alerts-cli/librato"
+	"sigs.k8s.io/yaml"
+)
+
+// outputFormats are the accepted values for --output.
+var outputFormats = []string{"text", "json", "ndjson", "yaml", "csv", "tsv"}
+
+// alert states derived from the status endpoint
+const (
+	stateFiring  = "firing"
+	stateCleared = "cleared"
+	stateOK      = "ok"
+)
+
+// alertRecord is an alert together with its current state, it is the unit
+// rendered by the machine readable outputs.
+type alertRecord struct {
+	librato.Alert
+	State       string `json:"state"`
+	TriggeredAt int    `json:"triggered_at,omitempty"`
+}
+
+// csvHeader are the columns written by the csv and tsv outputs.
+var csvHeader = []string{
+	"id", "name", "description", "active", "state", "triggered_at", "metrics",
+	"services", "rearm_seconds", "rearm_per_signal", "version", "created_at", "updated_at",
+}
+
+func validOutput(format string) bool {
+	for _, f := range outputFormats {
+		if f == format {
+			return true
+		}
+	}
+	return false
+}
+
+// outputTemplate is the template given with --format, nil when not used.
+var outputTemplate *template.Template
+
+// templateFuncs are the helpers available to --format templates.
+var templateFuncs = template.FuncMap{
+	"join":  strings.Join,
+	"upper": strings.ToUpper,
+	"lower": strings.ToLower,
+	// time renders an unix timestamp as RFC3339, empty when zero
+	"time": func(ts int) string {
+		return formatTime(time.RFC3339, ts)
+	},
+	// timefmt renders an unix timestamp with a go time layout
+	"timefmt": formatTime,
+	"json": func(v interface{}) (string, error) {
+		data, err := json.Marshal(v)
+		return string(data), err
+	},
+	"metrics": func(conditions []librato.Condition) []string {
+		metrics := make([]string, 0, len(conditions))
+		for _, condition := range conditions {
+			metrics = append(metrics, condition.MetricName)
+		}
+		return metrics
+	},
+	"services": func(services []librato.Service) []string {
+		titles := make([]string, 0, len(services))
+		for _, service := range services {
+			titles = append(titles, service.Title)
+		}
+		return titles
+	},
+}
+
+func formatTime(layout string, ts int) string {
+	if ts == 0 {
+		return ""
+	}
+	return time.Unix(int64(ts), 0).Format(layout)
+}
+
+// parseFormat compiles a --format template. Literal \t and \n sequences are
+// turned into tabs and newlines so they can be used from the shell.
+func parseFormat(format string) (*template.Template, error) {
+	format = strings.NewReplacer(`\t`, "\t", `\n`, "\n").Replace(format)
+	return template.New("format").Funcs(templateFuncs).Parse(format)
+}
+
+// machineOutput tells if the selected output is not the colored text one.
+func machineOutput() bool {
+	return opts.output != "text" || outputTemplate != nil
+}
+
+// newRecords builds records for alerts, setting their state from status when
+// it is not nil.
+func newRecords(alerts []librato.Alert, status *librato.Status) []alertRecord {
+	events := map[int]alertRecord{}
+	if status != nil {
+		for _, event := range status.Cleared {
+			events[event.ID] = alertRecord{State: stateCleared, TriggeredAt: event.TriggeredAt}
+		}
+		// firing wins over cleared for alerts in both lists
+		for _, event := range status.Firing {
+			events[event.ID] = alertRecord{State: stateFiring, TriggeredAt: event.TriggeredAt}
+		}
+	}
+
+	records := make([]alertRecord, 0, len(alerts))
+	for _, alert := range alerts {
+		record := alertRecord{Alert: alert, State: stateOK}
+		if event, ok := events[alert.ID]; ok {
+			record.State = event.State
+			record.TriggeredAt = event.TriggeredAt
+		}
+		records = append(records, record)
+	}
+	return records
+}
+
+// printRecords writes records to stdout in the selected output format.
+func printRecords(records []alertRecord) {
+	var err error
+	if outputTemplate != nil {
+		err = writeTemplate(os.Stdout, outputTemplate, records)
+	} else {
+		err = writeRecords(os.Stdout, opts.output, records)
+	}
+	if err != nil {
+		fatal("Error writing output: ", err)
+	}
+}
+
+// writeTemplate executes tmpl for every record, one per line.
+func writeTemplate(w io.Writer, tmpl *template.Template, records []alertRecord) error {
+	for _, record := range records {
+		if err := tmpl.Execute(w, record); err != nil {
+			return err
+		}
+		if _, err := fmt.Fprintln(w); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// writeRecords renders records to w in the given format.
+func writeRecords(w io.Writer, format string, records []alertRecord) error {
+	switch format {
+	case "json":
+		enc := json.NewEncoder(w)
+		enc.SetIndent("", "  ")
+		return enc.Encode(records)
+	case "ndjson":
+		enc := json.NewEncoder(w)
+		for _, record := range records {
+			if err := enc.Encode(record); err != nil {
+				return err
+			}
+		}
+		return nil
+	case "yaml":
+		data, err := yaml.Marshal(records)
+		if err != nil {
+			return err
+		}
+		_, err = w.Write(data)
+		return err
+	case "csv", "tsv":
+		cw := csv.NewWriter(w)
+		if format == "tsv" {
+			cw.Comma = '\t'
+		}
+		cw.Write(csvHeader)
+		for _, record := range records {
+			cw.Write(csvRow(record))
+		}
+		cw.Flush()
+		return cw.Error()
+	}
+	return fmt.Errorf("unknown output format %q", format)
+}
+
+func csvRow(record alertRecord) []string {
+	metrics := make([]string, 0, len(record.Conditions))
+	for _, condition := range record.Conditions {
+		metrics = append(metrics, condition.MetricName)
+	}
+	services := make([]string, 0, len(record.Services))
+	for _, service := range record.Services {
+		services = append(services, service.Title)
+	}
+	triggeredAt := ""
+	if record.TriggeredAt != 0 {
+		triggeredAt = strconv.Itoa(record.TriggeredAt)
+	}
+	return []string{
+		strconv.Itoa(record.ID),
+		record.Name,
+		record.Description,
+		strconv.FormatBool(record.Active),
+		record.State,
+		triggeredAt,
+		strings.Join(metrics, ","),
+		strings.Join(services, ","),
+		strconv.Itoa(record.RearmSeconds),
+		strconv.FormatBool(record.RearmPerSignal),
+		strconv.Itoa(record.Version),
+		strconv.Itoa(record.CreatedAt),
+		strconv.Itoa(record.UpdatedAt),
+	}
+}
@@ -0,0 +1,68 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"strconv"
+	"text/tabwriter"
+
+	"github.com/theist/librato-alerts-cli/librato"
+)
+
+// plan actions for alerts and names that won't change
+const (
+	planUnchanged = "unchanged"
+	planNotFound  = "not found"
+)
+
+// planEntry is one line of a dry run plan.
+type planEntry struct {
+	action string
+	id     int
+	name   string
+}
+
+// plan describes what a mutating mode would do without doing it.
+type plan []planEntry
+
+// add records that action would be applied to alert.
+func (p *plan) add(action string, alert librato.Alert) {
+	*p = append(*p, planEntry{action: action, id: alert.ID, name: alert.Name})
+}
+
+// addNotFound records input references that matched no alert.
+func (p *plan) addNotFound(refs []alertRef) {
+	for _, ref := range refs {
+		*p = append(*p, planEntry{action: planNotFound, id: ref.id, name: ref.name})
+	}
+}
+
+// write prints the plan as a table followed by a count of changes.
+func (p plan) write(w io.Writer) error {
+	tw := tabwriter.NewWriter(w, 0, 4, 2, ' ', 0)
+	fmt.Fprintln(tw, "ACTION\tID\tNAME")
+	changes := 0
+	for _, entry := range p {
+		id := "-"
+		if entry.id != 0 {
+			id = strconv.Itoa(entry.id)
+		}
+		fmt.Fprintf(tw, "%v\t%v\t%v\n", entry.action, id, entry.name)
+		if entry.action != planUnchanged && entry.action != planNotFound {
+			changes++
+		}
+	}
+	if err := tw.Flush(); err != nil {
+		return err
+	}
+	_, err := fmt.Fprintf(w, "\n%v alerts would change, dry run, nothing was updated\n", changes)
+	return err
+}
+
+// printPlan writes p to stdout.
+func printPlan(p plan) {
+	if err := p.write(os.Stdout); err != nil {
+		fatal("Error writing plan ", err)
+	}
+}
@@ -0,0 +1,218 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"log"
+	"path"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"github.com/theist/librato-alerts-cli/librato"
+)
+
+// selector picks alerts by the --match, --regex, --id, --metric and --service
+// flags. Repeating a flag matches any of its values, different flags must all
+// match.
+type selector struct {
+	globs    []string
+	regexps  []*regexp.Regexp
+	ids      map[int]bool
+	metrics  []string
+	services []string
+}
+
+// newSelector builds a selector from the selection flags in opts.
+func newSelector() (*selector, error) {
+	sel := &selector{
+		globs:    opts.match,
+		ids:      map[int]bool{},
+		metrics:  opts.metric,
+		services: opts.service,
+	}
+	for _, glob := range opts.match {
+		if _, err := path.Match(glob, ""); err != nil {
+			return nil, fmt.Errorf("invalid --match pattern %q: %w", glob, err)
+		}
+	}
+	for _, expr := range opts.regex {
+		re, err := regexp.Compile(expr)
+		if err != nil {
+			return nil, fmt.Errorf("invalid --regex %q: %w", expr, err)
+		}
+		sel.regexps = append(sel.regexps, re)
+	}
+	for _, rawID := range opts.id {
+		id, err := strconv.Atoi(rawID)
+		if err != nil {
+			return nil, fmt.Errorf("invalid --id %q: %w", rawID, err)
+		}
+		sel.ids[id] = true
+	}
+	return sel, nil
+}
+
+// empty tells if no selection flag was given.
+func (s *selector) empty() bool {
+	return len(s.globs) == 0 && len(s.regexps) == 0 && len(s.ids) == 0 &&
+		len(s.metrics) == 0 && len(s.services) == 0
+}
+
+// matches tells if alert satisfies every given selection flag.
+func (s *selector) matches(alert librato.Alert) bool {
+	if len(s.globs) > 0 && !anyString(s.globs, func(glob string) bool {
+		matched, _ := path.Match(glob, alert.Name)
+		return matched
+	}) {
+		return false
+	}
+	if len(s.regexps) > 0 {
+		found := false
+		for _, re := range s.regexps {
+			if re.MatchString(alert.Name) {
+				found = true
+				break
+			}
+		}
+		if !found {
+			return false
+		}
+	}
+	if len(s.ids) > 0 && !s.ids[alert.ID] {
+		return false
+	}
+	if len(s.metrics) > 0 && !anyString(s.metrics, func(metric string) bool {
+		for _, condition := range alert.Conditions {
+			if condition.MetricName == metric {
+				return true
+			}
+		}
+		return false
+	}) {
+		return false
+	}
+	if len(s.services) > 0 && !anyString(s.services, func(title string) bool {
+		for _, service := range alert.Services {
+			if service.Title == title {
+				return true
+			}
+		}
+		return false
+	}) {
+		return false
+	}
+	return true
+}
+
+func anyString(values []string, fn func(string) bool) bool {
+	for _, value := range values {
+		if fn(value) {
+			return true
+		}
+	}
+	return false
+}
+
+// selection is the result of resolving piped names and selection flags
+// against the alert list.
+type selection struct {
+	alerts []librato.Alert
+	// unmatched are piped references that matched no alert
+	unmatched []alertRef
+	// ambiguous are piped names shared by several alerts, all selected
+	ambiguous []ambiguousName
+}
+
+// ambiguousName is a piped name matching more than one alert.
+type ambiguousName struct {
+	name   string
+	alerts []librato.Alert
+}
+
+// selectAlerts returns the alerts referenced in refs, in that order, or every
+// alert when refs is nil, filtered by sel.
+func selectAlerts(alerts []librato.Alert, refs []alertRef, sel *selector) *selection {
+	result := &selection{}
+	seen := map[int]bool{}
+	add := func(alert librato.Alert) {
+		if !seen[alert.ID] && sel.matches(alert) {
+			seen[alert.ID] = true
+			result.alerts = append(result.alerts, alert)
+		}
+	}
+
+	if refs == nil {
+		for _, alert := range alerts {
+			add(alert)
+		}
+		return result
+	}
+	reported := map[string]bool{}
+	for _, ref := range refs {
+		var found []librato.Alert
+		for _, alert := range alerts {
+			if (ref.name == "" && alert.ID == ref.id) || (ref.name != "" && alert.Name == ref.name) {
+				found = append(found, alert)
+				add(alert)
+			}
+		}
+		switch {
+		case len(found) == 0:
+			result.unmatched = append(result.unmatched, ref)
+		case len(found) > 1 && !reported[ref.name]:
+			reported[ref.name] = true
+			result.ambiguous = append(result.ambiguous, ambiguousName{name: ref.name, alerts: found})
+		}
+	}
+	return result
+}
+
+// report logs the unmatched names, with suggestions from alerts, and the
+// ambiguous ones, returning true if there was any of them.
+func (s *selection) report(alerts []librato.Alert) bool {
+	for _, ref := range s.unmatched {
+		if ref.name == "" {
+			log.Printf("no alert with id %v", ref.id)
+			continue
+		}
+		msg := fmt.Sprintf("no alert named %q", ref.name)
+		if suggestions := suggestNames(ref.name, alerts); len(suggestions) > 0 {
+			for i, suggestion := range suggestions {
+				suggestions[i] = strconv.Quote(suggestion)
+			}
+			msg += ", did you mean " + strings.Join(suggestions, " or ") + "?"
+		}
+		log.Println(msg)
+	}
+	for _, ambiguous := range s.ambiguous {
+		ids := make([]string, 0, len(ambiguous.alerts))
+		for _, alert := range ambiguous.alerts {
+			ids = append(ids, strconv.Itoa(alert.ID))
+		}
+		log.Printf("%v alerts named %q (ids %v), all of them are selected", len(ambiguous.alerts), ambiguous.name, strings.Join(ids, ", "))
+	}
+	return len(s.unmatched) > 0 || len(s.ambiguous) > 0
+}
+
+// targetAlerts resolves the alerts a mutating mode works on, from the alerts
+// piped on stdin and the selection flags. An empty pipe, like the stdin of
+// scripts and CI jobs, leaves the selection to the flags and is an error
+// without them.
+func targetAlerts(alerts []librato.Alert, piped bool, r io.Reader) (*selection, error) {
+	sel, err := newSelector()
+	if err != nil {
+		return nil, err
+	}
+	var refs []alertRef
+	if piped {
+		refs, err = readAlertRefs(r, opts.inputFormat)
+		if err != nil {
+			return nil, err
+		}
+		if len(refs) == 0 && sel.empty() {
+			return nil, fmt.Errorf("no alerts were piped and no selection flags were given")
+		}
+	}
+	return selectAlerts(alerts, refs, sel), nil
+}
@@ -0,0 +1,63 @@
+package main
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/theist/librato-alerts-cli/librato"
+)
+
+func TestTargetAlerts(t *testing.T) {
+	alerts := []librato.Alert{
+		{ID: 1, Name: "prod.cpu"},
+		{ID: 2, Name: "prod.disk"},
+		{ID: 3, Name: "staging.cpu"},
+	}
+	tests := []struct {
+		name    string
+		piped   bool
+		input   string
+		regex   []string
+		want    []int
+		wantErr string
+	}{
+		{name: "flags only", regex: []string{"^prod"}, want: []int{1, 2}},
+		{name: "empty pipe with flags", piped: true, regex: []string{"^prod"}, want: []int{1, 2}},
+		{name: "blank pipe with flags", piped: true, input: "\n  \n", regex: []string{"^prod"}, want: []int{1, 2}},
+		{name: "empty pipe without flags", piped: true, wantErr: "no alerts were piped"},
+		{name: "pipe only", piped: true, input: "staging.cpu\n2\n", want: []int{3, 2}},
+		{name: "pipe filtered by flags", piped: true, input: "staging.cpu\nprod.cpu\n", regex: []string{"^prod"}, want: []int{1}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			saved := opts
+			defer func() { opts = saved }()
+			opts.regex = tt.regex
+			opts.inputFormat = "auto"
+
+			targets, err := targetAlerts(alerts, tt.piped, strings.NewReader(tt.input))
+			if tt.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Fatalf("got error %v, want %q", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			var ids []int
+			for _, alert := range targets.alerts {
+				ids = append(ids, alert.ID)
+			}
+			if len(ids) != len(tt.want) {
+				t.Fatalf("got alerts %v, want %v", ids, tt.want)
+			}
+			for i := range ids {
+				if ids[i] != tt.want[i] {
+					t.Errorf("got alerts %v, want %v", ids, tt.want)
+					break
+				}
+			}
+		})
+	}
+}
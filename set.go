@@ -0,0 +1,198 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"github.com/fatih/color"
+	"github.com/theist/librato-alerts-cli/librato"
+)
+
+var pathSegment = regexp.MustCompile(`^([A-Za-z0-9_-]+)((?:\[\d+\])*)$`)
+var pathIndex = regexp.MustCompile(`\[(\d+)\]`)
+
+// pathStep is a key of an object or, when key is empty, an index of a list.
+type pathStep struct {
+	key   string
+	index int
+}
+
+// assignment is a field=value argument of set.
+type assignment struct {
+	path  string
+	steps []pathStep
+	value string
+}
+
+// parseAssignments parses field=value arguments, fields being paths like
+// rearm_seconds or conditions[0].threshold.
+func parseAssignments(args []string) ([]assignment, error) {
+	var assignments []assignment
+	for _, arg := range args {
+		eq := strings.Index(arg, "=")
+		if eq <= 0 {
+			return nil, fmt.Errorf("%q is not a field=value assignment", arg)
+		}
+		a := assignment{path: arg[:eq], value: arg[eq+1:]}
+		for _, segment := range strings.Split(a.path, ".") {
+			match := pathSegment.FindStringSubmatch(segment)
+			if match == nil {
+				return nil, fmt.Errorf("invalid field %q", a.path)
+			}
+			a.steps = append(a.steps, pathStep{key: match[1]})
+			for _, index := range pathIndex.FindAllStringSubmatch(match[2], -1) {
+				i, _ := strconv.Atoi(index[1])
+				a.steps = append(a.steps, pathStep{index: i})
+			}
+		}
+		if hasString(librato.ReadOnlyFields, a.steps[0].key) {
+			return nil, fmt.Errorf("%v is set by the API and can't be changed", a.steps[0].key)
+		}
+		assignments = append(assignments, a)
+	}
+	return assignments, nil
+}
+
+// decode returns the value to assign, replacing current. Values replacing
+// strings are taken as they are unless quoted, other values are parsed as
+// JSON falling back to a string.
+func (a assignment) decode(current interface{}) interface{} {
+	if _, isString := current.(string); !isString || strings.HasPrefix(a.value, `"`) {
+		var value interface{}
+		if json.Unmarshal([]byte(a.value), &value) == nil {
+			return value
+		}
+	}
+	return a.value
+}
+
+// setPath sets the value at steps in doc, returning the updated doc.
+func (a assignment) setPath(doc interface{}, steps []pathStep) (interface{}, error) {
+	if len(steps) == 0 {
+		return a.decode(doc), nil
+	}
+	step := steps[0]
+	if step.key == "" {
+		list, ok := doc.([]interface{})
+		if !ok {
+			return nil, fmt.Errorf("%v: not a list", a.path)
+		}
+		if step.index >= len(list) {
+			return nil, fmt.Errorf("%v: index out of range, the list has %v items", a.path, len(list))
+		}
+		value, err := a.setPath(list[step.index], steps[1:])
+		if err != nil {
+			return nil, err
+		}
+		list[step.index] = value
+		return list, nil
+	}
+	object, ok := doc.(map[string]interface{})
+	if !ok {
+		if doc != nil {
+			return nil, fmt.Errorf("%v: not an object, can't set %v", a.path, step.key)
+		}
+		object = map[string]interface{}{}
+	}
+	value, err := a.setPath(object[step.key], steps[1:])
+	if err != nil {
+		return nil, err
+	}
+	object[step.key] = value
+	return object, nil
+}
+
+// setFields returns alert with the assignments applied, checking the result
+// is a valid alert.
+func setFields(alert librato.Alert, assignments []assignment) (librato.Alert, error) {
+	doc, err := jsonValue(alert)
+	if err != nil {
+		return alert, err
+	}
+	for _, a := range assignments {
+		if doc, err = a.setPath(doc, a.steps); err != nil {
+			return alert, err
+		}
+	}
+	data, err := json.Marshal(doc)
+	if err != nil {
+		return alert, err
+	}
+	if err := alert.SetFields(data); err != nil {
+		return alert, err
+	}
+	return alert, newDefinition(alert).validate()
+}
+
+// alertsSet changes fields of the alerts passed by stdin or selection flags,
+// showing the changes with --dry-run. Alerts changed by someone else since
+// they were listed are left untouched.
+func alertsSet(args []string) {
+	if len(args) == 0 {
+		usage("set mode requires field=value arguments, like rearm_seconds=3600")
+	}
+	assignments, err := parseAssignments(args)
+	if err != nil {
+		usage(err)
+	}
+	targets := resolveTargets()
+
+	updated := map[int]librato.Alert{}
+	changes := map[int][]fieldChange{}
+	for _, alert := range targets.alerts {
+		after, err := setFields(alert, assignments)
+		if err != nil {
+			fatalf("Can't set alert %v: %v", alert.Name, err)
+		}
+		if changes[alert.ID], err = diffFields(alert, after); err != nil {
+			fatal("Error comparing alerts > ", err)
+		}
+		updated[alert.ID] = after
+	}
+
+	if opts.dryRun {
+		count := 0
+		for _, alert := range targets.alerts {
+			if len(changes[alert.ID]) == 0 {
+				fmt.Printf("= %v (%v) unchanged\n", alert.Name, alert.ID)
+				continue
+			}
+			count++
+			fmt.Printf("%v %v (%v)\n", color.HiYellowString("~"), alert.Name, alert.ID)
+			writeChanges(os.Stdout, changes[alert.ID], "    ")
+		}
+		for _, ref := range targets.unmatched {
+			fmt.Printf("? %v not found\n", ref)
+		}
+		fmt.Printf("\n%v alerts would change, dry run, nothing was updated\n", count)
+		return
+	}
+
+	result := runBulk(targets.alerts, func(alert librato.Alert) (librato.Alert, bool, error) {
+		if len(changes[alert.ID]) == 0 {
+			printLine("alert " + alert.Name + " already set")
+			return alert, false, nil
+		}
+		if err := checkVersion(alert); isConflict(err) {
+			logLine(err.Error())
+			return alert, false, err
+		} else if err != nil {
+			logLine(fmt.Sprintf("Error getting alert %v: %v", alert.Name, err))
+			return alert, false, err
+		}
+		after := updated[alert.ID]
+		if err := client.Alerts.Update(context.Background(), &after); err != nil {
+			logLine(fmt.Sprintf("Error updating alert %v: %v", alert.Name, err))
+			return alert, false, err
+		}
+		printLine(alert.Name + " updated")
+		return after, true, nil
+	}, nil)
+	result.printSummary("updated", "already set", len(targets.unmatched))
+	result.exitOnFailure()
+}
@@ -0,0 +1,113 @@
+package main
+
+import (
+	"encoding/json"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestParseAssignments(t *testing.T) {
+	tests := []struct {
+		arg     string
+		path    string
+		steps   []pathStep
+		value   string
+		wantErr string
+	}{
+		{arg: "rearm_seconds=3600", path: "rearm_seconds", steps: []pathStep{{key: "rearm_seconds"}}, value: "3600"},
+		{arg: "conditions[0].threshold=95", path: "conditions[0].threshold", steps: []pathStep{{key: "conditions"}, {index: 0}, {key: "threshold"}}, value: "95"},
+		{arg: "conditions[1].tags[2].values[0]=prod", path: "conditions[1].tags[2].values[0]", steps: []pathStep{{key: "conditions"}, {index: 1}, {key: "tags"}, {index: 2}, {key: "values"}, {index: 0}}, value: "prod"},
+		{arg: "description=a=b", path: "description", steps: []pathStep{{key: "description"}}, value: "a=b"},
+		{arg: "description=", path: "description", steps: []pathStep{{key: "description"}}, value: ""},
+		{arg: "rearm_seconds", wantErr: "not a field=value assignment"},
+		{arg: "=3600", wantErr: "not a field=value assignment"},
+		{arg: "conditions[-1].threshold=95", wantErr: "invalid field"},
+		{arg: "conditions[x]=1", wantErr: "invalid field"},
+		{arg: "conditions..threshold=1", wantErr: "invalid field"},
+		{arg: "id=3", wantErr: "set by the API"},
+		{arg: "version=3", wantErr: "set by the API"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.arg, func(t *testing.T) {
+			assignments, err := parseAssignments([]string{tt.arg})
+			if tt.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Fatalf("got error %v, want %q", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			a := assignments[0]
+			if a.path != tt.path || a.value != tt.value || !reflect.DeepEqual(a.steps, tt.steps) {
+				t.Errorf("got %+v, want path %v steps %+v value %q", a, tt.path, tt.steps, tt.value)
+			}
+		})
+	}
+}
+
+func TestSetPath(t *testing.T) {
+	const doc = `{
+		"name": "cpu",
+		"rearm_seconds": 600,
+		"attributes": null,
+		"conditions": [{"threshold": 80, "tags": [{"name": "env", "values": ["prod"]}]}]
+	}`
+	tests := []struct {
+		arg     string
+		want    string
+		wantErr string
+	}{
+		{arg: "rearm_seconds=3600", want: `{"rearm_seconds": 3600}`},
+		{arg: "name=42", want: `{"name": "42"}`},
+		{arg: `name="quoted"`, want: `{"name": "quoted"}`},
+		{arg: "name=true", want: `{"name": "true"}`},
+		{arg: "conditions[0].threshold=95.5", want: `{"conditions": [{"threshold": 95.5, "tags": [{"name": "env", "values": ["prod"]}]}]}`},
+		{arg: "conditions[0].tags[0].values[0]=staging", want: `{"conditions": [{"threshold": 80, "tags": [{"name": "env", "values": ["staging"]}]}]}`},
+		{arg: "attributes.runbook_url=https://example.com", want: `{"attributes": {"runbook_url": "https://example.com"}}`},
+		{arg: "new_field=[1,2]", want: `{"new_field": [1, 2]}`},
+		{arg: "conditions[1].threshold=95", wantErr: "index out of range, the list has 1 items"},
+		{arg: "conditions[0].tags[0].values[3]=x", wantErr: "index out of range, the list has 1 items"},
+		{arg: "name[0]=x", wantErr: "not a list"},
+		{arg: "rearm_seconds.value=1", wantErr: "rearm_seconds.value: not an object, can't set value"},
+		{arg: "conditions.threshold=1", wantErr: "conditions.threshold: not an object"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.arg, func(t *testing.T) {
+			assignments, err := parseAssignments([]string{tt.arg})
+			if err != nil {
+				t.Fatal(err)
+			}
+			var value interface{}
+			if err := json.Unmarshal([]byte(doc), &value); err != nil {
+				t.Fatal(err)
+			}
+			got, err := assignments[0].setPath(value, assignments[0].steps)
+			if tt.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Fatalf("got error %v, want %q", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			var want map[string]interface{}
+			if err := json.Unmarshal([]byte(doc), &want); err != nil {
+				t.Fatal(err)
+			}
+			var changed map[string]interface{}
+			if err := json.Unmarshal([]byte(tt.want), &changed); err != nil {
+				t.Fatal(err)
+			}
+			for key, value := range changed {
+				want[key] = value
+			}
+			if !reflect.DeepEqual(got, want) {
+				t.Errorf("got %v, want %v", got, want)
+			}
+		})
+	}
+}
@@ -0,0 +1,117 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	"github.com/fatih/color"
+	"github.com/theist/librato-alerts-cli/librato"
+)
+
+// findAlert returns the alert referenced by ref, being it an alert id or an
+// alert name. Names matching several alerts are reported as an error.
+func findAlert(ref string) (*librato.Alert, error) {
+	if id, err := strconv.Atoi(ref); err == nil {
+		return client.Alerts.Get(context.Background(), id)
+	}
+
+	err, alerts := getAllAlertList()
+	if err != nil {
+		return nil, err
+	}
+	var found []librato.Alert
+	for _, alert := range *alerts {
+		if alert.Name == ref {
+			found = append(found, alert)
+		}
+	}
+	switch len(found) {
+	case 0:
+		return nil, fmt.Errorf("no alert named %q", ref)
+	case 1:
+		// fetched again to get the alert as the API returns it alone
+		return client.Alerts.Get(context.Background(), found[0].ID)
+	}
+	ids := make([]string, 0, len(found))
+	for _, alert := range found {
+		ids = append(ids, strconv.Itoa(alert.ID))
+	}
+	return nil, fmt.Errorf("%v alerts named %q, use one of the ids %v", len(found), ref, strings.Join(ids, ", "))
+}
+
+// showAlert prints the alert referenced in args in detail.
+func showAlert(args []string) {
+	if len(args) != 1 {
+		usage("show mode requires an alert name or id, like: librato-alerts-cli show <name|id>")
+	}
+	alert, err := findAlert(args[0])
+	if err != nil {
+		fatal("Error getting alert ", err)
+	}
+	err, statusRes := getStatus()
+	if err != nil {
+		fatal("Error getting status: ", err)
+	}
+	record := newRecords([]librato.Alert{*alert}, statusRes)[0]
+
+	if machineOutput() {
+		printRecords([]alertRecord{record})
+		return
+	}
+	printAlertDetail(record)
+}
+
+func printAlertDetail(record alertRecord) {
+	tw := tabwriter.NewWriter(os.Stdout, 0, 4, 2, ' ', 0)
+	fmt.Fprintf(tw, "Name:\t%v\n", color.HiYellowString(record.Name))
+	fmt.Fprintf(tw, "ID:\t%v\n", record.ID)
+	fmt.Fprintf(tw, "Description:\t%v\n", record.Description)
+	if record.Active {
+		fmt.Fprintf(tw, "Active:\t%v\n", color.HiGreenString("Active"))
+	} else {
+		fmt.Fprintf(tw, "Active:\t%v\n", color.HiRedString("Disabled"))
+	}
+	switch record.State {
+	case stateFiring:
+		fmt.Fprintf(tw, "State:\t%v since %v\n", color.HiRedString("Firing"), showTime(record.TriggeredAt))
+	case stateCleared:
+		fmt.Fprintf(tw, "State:\t%v, triggered at %v\n", color.GreenString("Cleared"), showTime(record.TriggeredAt))
+	default:
+		fmt.Fprintf(tw, "State:\t%v\n", "OK")
+	}
+	fmt.Fprintf(tw, "Rearm seconds:\t%v\n", record.RearmSeconds)
+	fmt.Fprintf(tw, "Rearm per signal:\t%v\n", record.RearmPerSignal)
+	fmt.Fprintf(tw, "Version:\t%v\n", record.Version)
+	fmt.Fprintf(tw, "Created at:\t%v\n", showTime(record.CreatedAt))
+	fmt.Fprintf(tw, "Updated at:\t%v\n", showTime(record.UpdatedAt))
+	tw.Flush()
+
+	fmt.Println("Conditions:")
+	tw = tabwriter.NewWriter(os.Stdout, 0, 4, 2, ' ', 0)
+	fmt.Fprintln(tw, "  TYPE\tMETRIC\tSOURCE\tTHRESHOLD\tDURATION\tSUMMARY FUNCTION")
+	for _, condition := range record.Conditions {
+		fmt.Fprintf(tw, "  %v\t%v\t%v\t%v\t%vs\t%v\n", condition.Type, condition.MetricName, condition.Source,
+			condition.Threshold, condition.Duration, condition.SummaryFunction)
+	}
+	tw.Flush()
+
+	fmt.Println("Services:")
+	tw = tabwriter.NewWriter(os.Stdout, 0, 4, 2, ' ', 0)
+	fmt.Fprintln(tw, "  ID\tTYPE\tTITLE")
+	for _, service := range record.Services {
+		fmt.Fprintf(tw, "  %v\t%v\t%v\n", service.ID, service.Type, service.Title)
+	}
+	tw.Flush()
+}
+
+func showTime(ts int) string {
+	if ts == 0 {
+		return "-"
+	}
+	return formatTime(time.RFC3339, ts)
+}
@@ -0,0 +1,210 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"os"
+	"text/tabwriter"
+	"time"
+
+	"github.com/theist/librato-alerts-cli/librato"
+)
+
+const snoozeStateFile = "snoozes.json"
+
+// snoozeEntry is an alert disabled by snooze, to be enabled again after
+// Until. Only alerts that were active when snoozed get an entry.
+type snoozeEntry struct {
+	ID        int       `json:"id"`
+	Name      string    `json:"name"`
+	APIURL    string    `json:"api_url"`
+	User      string    `json:"user"`
+	SnoozedAt time.Time `json:"snoozed_at"`
+	Until     time.Time `json:"until"`
+}
+
+// inAccount tells if the entry belongs to the account the client uses.
+func (e snoozeEntry) inAccount() bool {
+	return e.APIURL == client.BaseURL() && e.User == client.User()
+}
+
+type snoozeState struct {
+	Snoozes []snoozeEntry `json:"snoozes"`
+}
+
+func loadSnoozes() *snoozeState {
+	state := &snoozeState{}
+	if err := readState(snoozeStateFile, state); err != nil {
+		fatal("Error reading snooze state ", err)
+	}
+	return state
+}
+
+func (s *snoozeState) save() {
+	if err := writeState(snoozeStateFile, s); err != nil {
+		fatal("Error writing snooze state ", err)
+	}
+}
+
+// find returns the entry of alert id in the current account, or nil.
+func (s *snoozeState) find(id int) *snoozeEntry {
+	for i := range s.Snoozes {
+		if s.Snoozes[i].ID == id && s.Snoozes[i].inAccount() {
+			return &s.Snoozes[i]
+		}
+	}
+	return nil
+}
+
+// remove drops the entry of alert id in the current account.
+func (s *snoozeState) remove(id int) {
+	kept := s.Snoozes[:0]
+	for _, entry := range s.Snoozes {
+		if entry.ID != id || !entry.inAccount() {
+			kept = append(kept, entry)
+		}
+	}
+	s.Snoozes = kept
+}
+
+// alertsSnooze disables the selected alerts recording when they should be
+// enabled again by resume. Alerts already snoozed get their expiry extended.
+func alertsSnooze() {
+	if opts.snoozeFor <= 0 {
+		usage("snooze mode requires a positive --for duration, like --for 45m")
+	}
+	targets := resolveTargets()
+	state := loadSnoozes()
+	now := time.Now()
+	until := now.Add(opts.snoozeFor)
+
+	if opts.dryRun {
+		var p plan
+		for _, alert := range targets.alerts {
+			switch {
+			case alert.Active:
+				p.add("snooze", alert)
+			case state.find(alert.ID) != nil:
+				p.add("extend", alert)
+			default:
+				p.add(planUnchanged, alert)
+			}
+		}
+		p.addNotFound(targets.unmatched)
+		printPlan(p)
+		return
+	}
+
+	var active, inactive []librato.Alert
+	for _, alert := range targets.alerts {
+		if !alert.Active {
+			inactive = append(inactive, alert)
+		}
+		switch entry := state.find(alert.ID); {
+		case alert.Active:
+			active = append(active, alert)
+		case entry != nil:
+			entry.Until = until
+			fmt.Println("alert " + alert.Name + " already snoozed, extending it")
+		default:
+			fmt.Println("alert " + alert.Name + " already disabled, it won't be enabled by resume")
+		}
+	}
+	state.save()
+
+	result := setActive(active, false, func(alert librato.Alert) {
+		// alerts enabled by hand while snoozed already have an entry
+		if entry := state.find(alert.ID); entry != nil {
+			entry.Name = alert.Name
+			entry.SnoozedAt = now
+			entry.Until = until
+			state.save()
+			return
+		}
+		state.Snoozes = append(state.Snoozes, snoozeEntry{
+			ID:        alert.ID,
+			Name:      alert.Name,
+			APIURL:    client.BaseURL(),
+			User:      client.User(),
+			SnoozedAt: now,
+			Until:     until,
+		})
+		// saved after every alert so a failure in the middle of the run
+		// never loses track of the alerts already disabled
+		state.save()
+	})
+	result.unchanged = append(result.unchanged, inactive...)
+	result.printSummary("snoozed", "already disabled", len(targets.unmatched))
+	fmt.Printf("alerts will be enabled again by resume after %v\n", until.Format(time.RFC3339))
+	result.exitOnFailure()
+}
+
+// alertsResume enables the alerts whose snooze expired, unless somebody
+// enabled them in the meantime.
+func alertsResume() {
+	state := loadSnoozes()
+	now := time.Now()
+
+	var (
+		p       plan
+		expired []librato.Alert
+	)
+	for _, entry := range append([]snoozeEntry(nil), state.Snoozes...) {
+		if !entry.inAccount() || entry.Until.After(now) {
+			continue
+		}
+		alert, err := client.Alerts.Get(context.Background(), entry.ID)
+		if librato.IsNotFound(err) && !opts.dryRun {
+			log.Printf("alert %v no longer exists, forgetting its snooze", entry.Name)
+			state.remove(entry.ID)
+			state.save()
+			continue
+		}
+		if err != nil {
+			log.Printf("Error getting alert %v, skipping it: %v", entry.Name, err)
+			continue
+		}
+		if alert.Active {
+			p.add(planUnchanged, *alert)
+		} else {
+			p.add("enable", *alert)
+		}
+		expired = append(expired, *alert)
+	}
+	if opts.dryRun {
+		printPlan(p)
+		return
+	}
+
+	forget := func(alert librato.Alert) {
+		state.remove(alert.ID)
+		state.save()
+	}
+	result := setActive(expired, true, forget)
+	// enabled by somebody else meanwhile, nothing left to resume
+	for _, alert := range result.unchanged {
+		forget(alert)
+	}
+	result.printSummary("enabled", "already enabled", 0)
+	result.exitOnFailure()
+}
+
+// printSnoozed lists the alerts snoozed in the current account.
+func printSnoozed() {
+	state := loadSnoozes()
+	now := time.Now()
+	tw := tabwriter.NewWriter(os.Stdout, 0, 4, 2, ' ', 0)
+	fmt.Fprintln(tw, "ID\tNAME\tUNTIL\t")
+	for _, entry := range state.Snoozes {
+		if !entry.inAccount() {
+			continue
+		}
+		expired := ""
+		if !entry.Until.After(now) {
+			expired = "expired"
+		}
+		fmt.Fprintf(tw, "%v\t%v\t%v\t%v\n", entry.ID, entry.Name, entry.Until.Local().Format(time.RFC3339), expired)
+	}
+	tw.Flush()
+}
@@ -0,0 +1,72 @@
+package main
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+
+	"github.com/mitchellh/go-homedir"
+)
+
+// stateDir returns the directory where local state like snoozes is kept,
+// LIBRATO_STATE_DIR or ~/.librato-alerts-cli.d by default.
+func stateDir() (string, error) {
+	return homedir.Expand(envOr("LIBRATO_STATE_DIR", "~/.librato-alerts-cli.d"))
+}
+
+// statePath returns the path of name inside the state dir.
+func statePath(name string) (string, error) {
+	dir, err := stateDir()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(dir, name), nil
+}
+
+// readState decodes the JSON state file name into v, leaving v untouched
+// when the file does not exist yet.
+func readState(name string, v interface{}) error {
+	path, err := statePath(name)
+	if err != nil {
+		return err
+	}
+	data, err := ioutil.ReadFile(path)
+	if os.IsNotExist(err) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	return json.Unmarshal(data, v)
+}
+
+// writeState stores v as JSON in the state file name, replacing it
+// atomically so an interrupted write never leaves a truncated file.
+func writeState(name string, v interface{}) error {
+	path, err := statePath(name)
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(filepath.Dir(path), 0700); err != nil {
+		return err
+	}
+	data, err := json.MarshalIndent(v, "", "  ")
+	if err != nil {
+		return err
+	}
+	tmp, err := ioutil.TempFile(filepath.Dir(path), filepath.Base(path)+".*")
+	if err != nil {
+		return err
+	}
+	if _, err := tmp.Write(data); err != nil {
+		tmp.Close()
+		os.Remove(tmp.Name())
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		os.Remove(tmp.Name())
+		return err
+	}
+	return os.Rename(tmp.Name(), path)
+}
@@ -0,0 +1,78 @@
+package main
+
+import (
+	"sort"
+	"strings"
+
+	"github.com/theist/librato-alerts-cli/librato"
+)
+
+// maxSuggestions limits the "did you mean" alternatives given for a name.
+const maxSuggestions = 3
+
+// suggestNames returns the alert names closest to name by edit distance,
+// ignoring case, only the ones close enough to be a likely typo.
+func suggestNames(name string, alerts []librato.Alert) []string {
+	type candidate struct {
+		name     string
+		distance int
+	}
+	limit := len([]rune(name)) / 3
+	if limit < 2 {
+		limit = 2
+	}
+
+	var candidates []candidate
+	seen := map[string]bool{}
+	for _, alert := range alerts {
+		if seen[alert.Name] {
+			continue
+		}
+		seen[alert.Name] = true
+		distance := editDistance(strings.ToLower(name), strings.ToLower(alert.Name))
+		if distance <= limit {
+			candidates = append(candidates, candidate{name: alert.Name, distance: distance})
+		}
+	}
+	sort.SliceStable(candidates, func(i, j int) bool {
+		return candidates[i].distance < candidates[j].distance
+	})
+
+	var names []string
+	for i := 0; i < len(candidates) && i < maxSuggestions; i++ {
+		names = append(names, candidates[i].name)
+	}
+	return names
+}
+
+// editDistance is the Levenshtein distance between a and b.
+func editDistance(a, b string) int {
+	ra, rb := []rune(a), []rune(b)
+	prev := make([]int, len(rb)+1)
+	curr := make([]int, len(rb)+1)
+	for j := range prev {
+		prev[j] = j
+	}
+	for i := 1; i <= len(ra); i++ {
+		curr[0] = i
+		for j := 1; j <= len(rb); j++ {
+			cost := 1
+			if ra[i-1] == rb[j-1] {
+				cost = 0
+			}
+			curr[j] = minInt(prev[j]+1, curr[j-1]+1, prev[j-1]+cost)
+		}
+		prev, curr = curr, prev
+	}
+	return prev[len(rb)]
+}
+
+func minInt(first int, rest ...int) int {
+	min := first
+	for _, v := range rest {
+		if v < min {
+			min = v
+		}
+	}
+	return min
+}
@@ -0,0 +1,280 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"log"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"text/tabwriter"
+	"time"
+
+	"github.com/theist/librato-alerts-cli/librato"
+)
+
+const trashDir = "trash"
+
+// trashEntry is the backup of a deleted alert, written to the trash dir
+// before deleting it so restore-deleted can create it again.
+type trashEntry struct {
+	ID        int             `json:"id"`
+	Name      string          `json:"name"`
+	APIURL    string          `json:"api_url"`
+	User      string          `json:"user"`
+	DeletedAt time.Time       `json:"deleted_at"`
+	Alert     json.RawMessage `json:"alert"`
+
+	// file is the name of the entry in the trash dir
+	file string
+}
+
+// inAccount tells if the entry belongs to the account the client uses.
+func (e trashEntry) inAccount() bool {
+	return e.APIURL == client.BaseURL() && e.User == client.User()
+}
+
+// trashAlert writes the backup of alert to the trash dir, returning its
+// file name there.
+func trashAlert(alert librato.Alert) (string, error) {
+	doc := alert.Raw()
+	if doc == nil {
+		var err error
+		if doc, err = json.Marshal(alert); err != nil {
+			return "", err
+		}
+	}
+	now := time.Now()
+	entry := trashEntry{
+		ID:        alert.ID,
+		Name:      alert.Name,
+		APIURL:    client.BaseURL(),
+		User:      client.User(),
+		DeletedAt: now.UTC(),
+		Alert:     doc,
+	}
+	file := fmt.Sprintf("%v-%v.json", now.Format("20060102T150405.000000000"), alert.ID)
+	return file, writeState(filepath.Join(trashDir, file), entry)
+}
+
+// loadTrash returns the trash entries of the current account, oldest first.
+func loadTrash() []trashEntry {
+	dir, err := statePath(trashDir)
+	if err != nil {
+		fatal("Error reading trash ", err)
+	}
+	files, err := ioutil.ReadDir(dir)
+	if err != nil && !os.IsNotExist(err) {
+		fatal("Error reading trash ", err)
+	}
+	var entries []trashEntry
+	for _, f := range files {
+		if f.IsDir() || filepath.Ext(f.Name()) != ".json" {
+			continue
+		}
+		var entry trashEntry
+		if err := readState(filepath.Join(trashDir, f.Name()), &entry); err != nil {
+			fatal("Error reading trash ", err)
+		}
+		if entry.inAccount() {
+			entry.file = f.Name()
+			entries = append(entries, entry)
+		}
+	}
+	sort.SliceStable(entries, func(i, j int) bool { return entries[i].DeletedAt.Before(entries[j].DeletedAt) })
+	return entries
+}
+
+// removeTrash deletes the trash file name.
+func removeTrash(name string) error {
+	path, err := statePath(filepath.Join(trashDir, name))
+	if err != nil {
+		return err
+	}
+	return os.Remove(path)
+}
+
+// alertsDelete deletes the selected alerts once confirmed, backing each one
+// up to the trash dir first.
+func alertsDelete() {
+	targets := resolveTargets()
+	if opts.dryRun {
+		var p plan
+		for _, alert := range targets.alerts {
+			p.add("delete", alert)
+		}
+		p.addNotFound(targets.unmatched)
+		printPlan(p)
+		return
+	}
+	if len(targets.alerts) > 0 && !opts.yes {
+		if !haveTerminal() {
+			usage("No terminal to ask for confirmation, pass --yes to go on")
+		}
+		for _, alert := range targets.alerts {
+			fmt.Printf("%v (%v)\n", alert.Name, alert.ID)
+		}
+		if !confirm(fmt.Sprintf("Delete these %v alerts?", len(targets.alerts))) {
+			fmt.Println("nothing was deleted")
+			return
+		}
+	}
+
+	result := runBulk(targets.alerts, func(alert librato.Alert) (librato.Alert, bool, error) {
+		deleted, err := trashAndDelete(alert)
+		if err != nil {
+			return alert, false, err
+		}
+		printLine(deleted.Name + " deleted")
+		return deleted, true, nil
+	}, nil)
+	result.printSummary("deleted", "kept", len(targets.unmatched))
+	result.exitOnFailure()
+}
+
+// trashAndDelete deletes alert once the backup of its current version is
+// in the trash dir, returning the alert deleted. Failures are logged.
+func trashAndDelete(alert librato.Alert) (librato.Alert, error) {
+	// the backup is taken from the current version, which may have been
+	// edited since the alert was listed
+	current, _, err := freshAlert(alert)
+	if err != nil {
+		logLine(fmt.Sprintf("Error getting alert %v, not deleting it: %v", alert.Name, err))
+		return alert, err
+	}
+	alert = *current
+	file, err := trashAlert(alert)
+	if err != nil {
+		logLine(fmt.Sprintf("Error backing up alert %v, not deleting it: %v", alert.Name, err))
+		return alert, err
+	}
+	err = client.Alerts.Delete(context.Background(), alert.ID)
+	if librato.IsNotFound(err) {
+		// a retried DELETE finds the alert gone when an earlier attempt
+		// deleted it despite failing
+		return alert, nil
+	}
+	if err != nil {
+		// the DELETE may have gone through anyway, so the backup is only
+		// dropped once the alert is seen still there
+		_, getErr := client.Alerts.Get(context.Background(), alert.ID)
+		switch {
+		case getErr == nil:
+			logLine(fmt.Sprintf("Error deleting alert %v: %v", alert.Name, err))
+			removeTrash(file)
+		case librato.IsNotFound(getErr):
+			return alert, nil
+		default:
+			logLine(fmt.Sprintf("Error deleting alert %v, its backup is kept in case it was deleted: %v", alert.Name, err))
+		}
+		return alert, err
+	}
+	return alert, nil
+}
+
+// restoreDeleted creates again the deleted alerts given by id or name in
+// args, or every one in the trash with --all, listing the trash otherwise.
+// Alerts deleted several times are restored from their last backup, which
+// is sent as it is apart from the fields set by the API.
+func restoreDeleted(args []string) {
+	entries := loadTrash()
+	if len(args) == 0 && !opts.all {
+		printTrash(entries)
+		return
+	}
+
+	latest := map[int]trashEntry{}
+	for _, entry := range entries {
+		latest[entry.ID] = entry
+	}
+	var restore []trashEntry
+	for _, arg := range args {
+		found := false
+		for _, entry := range entries {
+			if strconv.Itoa(entry.ID) == arg || entry.Name == arg {
+				found = true
+			}
+		}
+		if !found {
+			fatalf("No deleted alert %v in the trash", arg)
+		}
+	}
+	for _, entry := range entries {
+		if latest[entry.ID].file != entry.file {
+			continue
+		}
+		if opts.all || hasString(args, strconv.Itoa(entry.ID)) || hasString(args, entry.Name) {
+			restore = append(restore, entry)
+		}
+	}
+
+	err, alerts := getAllAlertList()
+	if err != nil {
+		fatal("Error getting alerts > ", err)
+	}
+	existing := map[string]bool{}
+	for _, alert := range *alerts {
+		existing[alert.Name] = true
+	}
+
+	if opts.dryRun {
+		var p plan
+		for _, entry := range restore {
+			action := "restore"
+			if existing[entry.Name] {
+				action = planUnchanged
+			}
+			p = append(p, planEntry{action: action, id: entry.ID, name: entry.Name})
+		}
+		printPlan(p)
+		return
+	}
+
+	restored, failed := 0, 0
+	for _, entry := range restore {
+		if existing[entry.Name] {
+			fmt.Printf("an alert named %v exists, not restoring it\n", entry.Name)
+			continue
+		}
+		var alert librato.Alert
+		if err := json.Unmarshal(entry.Alert, &alert); err != nil {
+			failed++
+			log.Printf("Error reading backup of alert %v: %v", entry.Name, err)
+			continue
+		}
+		created, err := client.Alerts.Create(context.Background(), &alert)
+		if err != nil {
+			failed++
+			log.Printf("Error restoring alert %v: %v", entry.Name, err)
+			continue
+		}
+		existing[entry.Name] = true
+		restored++
+		fmt.Printf("%v restored with id %v\n", created.Name, created.ID)
+		if err := removeTrash(entry.file); err != nil {
+			log.Printf("Error removing backup of alert %v: %v", entry.Name, err)
+		}
+	}
+	fmt.Printf("%v restored, %v skipped, %v failed\n", restored, len(restore)-restored-failed, failed)
+	if failed > 0 {
+		os.Exit(exitError)
+	}
+}
+
+// printTrash lists the deleted alerts that can be restored.
+func printTrash(entries []trashEntry) {
+	if len(entries) == 0 {
+		fmt.Println("No deleted alerts in the trash")
+		return
+	}
+	tw := tabwriter.NewWriter(os.Stdout, 0, 4, 2, ' ', 0)
+	fmt.Fprintln(tw, "ID\tNAME\tDELETED")
+	for _, entry := range entries {
+		fmt.Fprintf(tw, "%v\t%v\t%v\n", entry.ID, entry.Name, entry.DeletedAt.Local().Format(time.RFC3339))
+	}
+	tw.Flush()
+	fmt.Println("\nRestore them with restore-deleted <id|name>... or restore-deleted --all")
+}
@@ -0,0 +1,97 @@
+package main
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+
+	"github.com/theist/librato-alerts-cli/librato"
+)
+
+// deleteServer serves a single alert, answering DELETE requests with the
+// given statuses in turn. A DELETE only removes the alert when deletes says
+// so for that attempt, as a server failing after deleting it would.
+type deleteServer struct {
+	mu       sync.Mutex
+	exists   bool
+	statuses []int
+	deletes  []bool
+	getFails bool
+}
+
+func (s *deleteServer) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if r.URL.Path != "/v1/alerts/1" {
+		http.NotFound(w, r)
+		return
+	}
+	switch r.Method {
+	case http.MethodGet:
+		if s.getFails && len(s.statuses) == 0 {
+			w.WriteHeader(http.StatusServiceUnavailable)
+			return
+		}
+		if !s.exists {
+			http.NotFound(w, r)
+			return
+		}
+		w.Write([]byte(`{"id": 1, "name": "cpu", "version": 3, "conditions": []}`))
+	case http.MethodDelete:
+		if !s.exists {
+			http.NotFound(w, r)
+			return
+		}
+		status := s.statuses[0]
+		if s.deletes[0] {
+			s.exists = false
+		}
+		s.statuses, s.deletes = s.statuses[1:], s.deletes[1:]
+		w.WriteHeader(status)
+	}
+}
+
+func TestTrashAndDelete(t *testing.T) {
+	tests := []struct {
+		name     string
+		statuses []int
+		deletes  []bool
+		getFails bool
+		retries  int
+		wantErr  bool
+		kept     bool
+	}{
+		{name: "deleted", statuses: []int{http.StatusNoContent}, deletes: []bool{true}, kept: true},
+		{name: "deleted despite failing", statuses: []int{http.StatusServiceUnavailable}, deletes: []bool{true}, kept: true},
+		{name: "retry finds it deleted", statuses: []int{http.StatusServiceUnavailable}, deletes: []bool{true}, retries: 1, kept: true},
+		{name: "not deleted", statuses: []int{http.StatusInternalServerError}, deletes: []bool{false}, wantErr: true},
+		{name: "unknown outcome", statuses: []int{http.StatusServiceUnavailable}, deletes: []bool{false}, getFails: true, wantErr: true, kept: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Setenv("LIBRATO_STATE_DIR", t.TempDir())
+			server := httptest.NewServer(&deleteServer{exists: true, statuses: tt.statuses, deletes: tt.deletes, getFails: tt.getFails})
+			defer server.Close()
+			saved := client
+			defer func() { client = saved }()
+			var err error
+			client, err = librato.NewClient("user@example.com", "secret", librato.WithBaseURL(server.URL+"/v1"), librato.WithMaxRetries(tt.retries))
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			deleted, err := trashAndDelete(librato.Alert{ID: 1, Name: "cpu", Version: 3})
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("got error %v, want error %v", err, tt.wantErr)
+			}
+			if deleted.ID != 1 {
+				t.Errorf("got alert %+v", deleted)
+			}
+			entries := loadTrash()
+			if kept := len(entries) == 1; kept != tt.kept {
+				t.Errorf("got %v trash entries, want backup kept %v", len(entries), tt.kept)
+			}
+		})
+	}
+}
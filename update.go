@@ -0,0 +1,256 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"os"
+	"sync"
+
+	"github.com/mattn/go-isatty"
+	"github.com/theist/librato-alerts-cli/librato"
+)
+
+// updateFunc updates one alert, returning it as updated and false when it was
+// already in the wanted state and nothing was done.
+type updateFunc func(alert librato.Alert) (librato.Alert, bool, error)
+
+// bulkFailure is an alert a bulk update failed to update.
+type bulkFailure struct {
+	alert librato.Alert
+	err   error
+}
+
+// bulkResult is the outcome of a bulk update.
+type bulkResult struct {
+	changed   []librato.Alert
+	unchanged []librato.Alert
+	failed    []bulkFailure
+}
+
+// runBulk applies update to alerts using up to --parallel concurrent
+// workers, going on after failures. onChanged, when not nil, is called for
+// every changed alert, never concurrently.
+func runBulk(alerts []librato.Alert, update updateFunc, onChanged func(librato.Alert)) *bulkResult {
+	result := &bulkResult{}
+	progress := newReporter(len(alerts))
+	defer progress.finish()
+
+	workers := opts.parallel
+	if workers < 1 {
+		workers = 1
+	}
+	jobs := make(chan librato.Alert)
+	var (
+		mu sync.Mutex
+		wg sync.WaitGroup
+	)
+	for i := 0; i < workers && i < len(alerts); i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for alert := range jobs {
+				updated, changed, err := update(alert)
+				mu.Lock()
+				switch {
+				case err != nil:
+					result.failed = append(result.failed, bulkFailure{alert: alert, err: err})
+				case changed:
+					result.changed = append(result.changed, updated)
+					if onChanged != nil {
+						onChanged(updated)
+					}
+				default:
+					result.unchanged = append(result.unchanged, alert)
+				}
+				mu.Unlock()
+				progress.step()
+			}
+		}()
+	}
+	for _, alert := range alerts {
+		jobs <- alert
+	}
+	close(jobs)
+	wg.Wait()
+	return result
+}
+
+// printSummary reports the counts of the result, using done and unchanged
+// to name the changed and unchanged alerts, and notFound input names that
+// matched no alert.
+func (r *bulkResult) printSummary(done, unchanged string, notFound int) {
+	fmt.Printf("%v %v, %v %v, %v failed, %v not found\n",
+		len(r.changed), done, len(r.unchanged), unchanged, len(r.failed), notFound)
+}
+
+// exitOnFailure exits, with the code matching the first failure, when any
+// alert failed to update.
+func (r *bulkResult) exitOnFailure() {
+	if len(r.failed) == 0 {
+		return
+	}
+	os.Exit(errorExitCode([]interface{}{r.failed[0].err}))
+}
+
+// activeVerbs returns the words used to report enabling or disabling alerts.
+func activeVerbs(active bool) (verb, done string) {
+	if active {
+		return "enable", "enabled"
+	}
+	return "disable", "disabled"
+}
+
+// setActive updates the alerts not already in the active state.
+func setActive(alerts []librato.Alert, active bool, onChanged func(librato.Alert)) *bulkResult {
+	_, done := activeVerbs(active)
+	return runBulk(alerts, func(alert librato.Alert) (librato.Alert, bool, error) {
+		if alert.Active == active {
+			printLine("alert " + alert.Name + " already " + done)
+			return alert, false, nil
+		}
+		// the alert may have been edited since it was listed, as only the
+		// active flag changes its current version is updated instead
+		current, changed, err := freshAlert(alert)
+		if err != nil {
+			logLine(fmt.Sprintf("Error getting alert %v: %v", alert.Name, err))
+			return alert, false, err
+		}
+		if changed {
+			logLine(fmt.Sprintf("alert %v changed since it was read, using its version %v", alert.Name, current.Version))
+			if current.Active == active {
+				printLine("alert " + alert.Name + " already " + done)
+				return *current, false, nil
+			}
+			alert = *current
+		}
+		alert.Active = active
+		if err := client.Alerts.Update(context.Background(), &alert); err != nil {
+			logLine(fmt.Sprintf("Error updating alert %v: %v", alert.Name, err))
+			return alert, false, err
+		}
+		printLine(alert.Name + " " + done)
+		return alert, true, nil
+	}, onChanged)
+}
+
+// conflictError is returned when an alert changed in the API since it was
+// read, so writing it would overwrite someone else's changes.
+type conflictError struct {
+	name             string
+	version, current int
+}
+
+func (e *conflictError) Error() string {
+	return fmt.Sprintf("alert %v was changed by someone else since it was read, version %v is now %v, not overwriting it",
+		e.name, e.version, e.current)
+}
+
+// isConflict tells if err is a conflictError.
+func isConflict(err error) bool {
+	var conflict *conflictError
+	return errors.As(err, &conflict)
+}
+
+// freshAlert returns alert as it is now in the API and whether its version
+// changed since alert was read.
+func freshAlert(alert librato.Alert) (*librato.Alert, bool, error) {
+	current, err := client.Alerts.Get(context.Background(), alert.ID)
+	if err != nil {
+		return nil, false, err
+	}
+	return current, current.Version != alert.Version, nil
+}
+
+// checkVersion fails with a conflictError when alert changed in the API
+// since it was read.
+func checkVersion(alert librato.Alert) error {
+	current, changed, err := freshAlert(alert)
+	if err != nil {
+		return err
+	}
+	if changed {
+		return &conflictError{name: alert.Name, version: alert.Version, current: current.Version}
+	}
+	return nil
+}
+
+// reporter shows the progress of a bulk update in the last line of the
+// terminal when stderr is one, keeping it below the regular output.
+type reporter struct {
+	mu    sync.Mutex
+	tty   bool
+	total int
+	done  int
+}
+
+// activeReporter is the reporter of the bulk update in progress, if any.
+var (
+	activeReporter   *reporter
+	activeReporterMu sync.Mutex
+)
+
+func newReporter(total int) *reporter {
+	r := &reporter{
+		tty:   total > 1 && isatty.IsTerminal(os.Stderr.Fd()),
+		total: total,
+	}
+	activeReporterMu.Lock()
+	activeReporter = r
+	activeReporterMu.Unlock()
+	return r
+}
+
+func (r *reporter) step() {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.done++
+	r.draw()
+}
+
+func (r *reporter) draw() {
+	if r.tty {
+		fmt.Fprintf(os.Stderr, "\r\033[K[%v/%v] updating alerts", r.done, r.total)
+	}
+}
+
+func (r *reporter) clear() {
+	if r.tty {
+		fmt.Fprint(os.Stderr, "\r\033[K")
+	}
+}
+
+func (r *reporter) finish() {
+	r.mu.Lock()
+	r.clear()
+	r.mu.Unlock()
+	activeReporterMu.Lock()
+	activeReporter = nil
+	activeReporterMu.Unlock()
+}
+
+// printLine prints msg to stdout without garbling the progress line.
+func printLine(msg string) {
+	withReporter(func() { fmt.Println(msg) })
+}
+
+// logLine logs msg without garbling the progress line.
+func logLine(msg string) {
+	withReporter(func() { log.Println(msg) })
+}
+
+func withReporter(fn func()) {
+	activeReporterMu.Lock()
+	r := activeReporter
+	activeReporterMu.Unlock()
+	if r == nil {
+		fn()
+		return
+	}
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.clear()
+	fn()
+	r.draw()
+}